@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"k8s.io/client-go/dynamic"
@@ -17,6 +19,38 @@ import (
 type NSClonerRequestBody struct {
 	//SourceNamespace string `json:"sourceNamespace"`
 	TargetNamespace string `json:"targetNamespace"`
+	managers.CloneOptions
+}
+
+type ExtendRequestBody struct {
+	// Added to the current expiry, e.g. "24h"
+	TTL string `json:"ttl"`
+	// Replaces the current expiry
+	ExpiresAt *time.Time `json:"expiresAt"`
+	// Cluster of the clone, the source cluster when empty
+	TargetCluster string `json:"targetCluster"`
+}
+
+type ScaleRequestBody struct {
+	Namespace string `json:"namespace"`
+	// Scale to this count instead of by one replica
+	Replicas *int32 `json:"replicas"`
+}
+
+type NamespaceScaleRequestBody struct {
+	// Set every workload to this count
+	Replicas *int32 `json:"replicas"`
+	// Set every workload back to the replica count of its source workload
+	Restore bool `json:"restore"`
+}
+
+type MirrorRequestBody struct {
+	// Source or cloned VirtualServices to mirror, all cloned VirtualServices when empty
+	VirtualServices []string `json:"virtualServices"`
+	// Names of the http routes to mirror, all routes when empty
+	Routes []string `json:"routes"`
+	// Percentage of requests to mirror, 100 when not set
+	Percentage float64 `json:"percentage"`
 }
 
 type DeploymentPatchRequestBody struct {
@@ -39,7 +73,6 @@ type ConfigMapPatchRequestBody struct {
 }
 
 var (
-	nsRequestBody              NSClonerRequestBody
 	deploymentPatchRequestBody DeploymentPatchRequestBody
 	secretPatchRequestBody     SecretPatchRequestBody
 	configMapPatchRequestBody  ConfigMapPatchRequestBody
@@ -83,36 +116,336 @@ func GetDeployments(c *gin.Context) {
 }
 
 // @Summary Clone a namespace
-// @Description Clone a namespace and its objects to a new namespace
+// @Description Start an asynchronous job cloning a namespace and its objects to a new namespace.
+// @Description With dryRun set, the clone is planned synchronously and the plan is returned instead.
 // @Accept json
 // @Produce json
+// @Param namespace path string true "Source namespace name"
 // @Param body body NSClonerRequestBody true "Namespace clone request body"
-// @Success 200 {object} string
+// @Success 202 {object} string
+// @Success 200 {object} managers.CloneJobStatus
 // @Router /namespaces/:namespace/cloneNamespace [post]
 func CloneNamespace(c *gin.Context) {
 	clientset := c.MustGet("clientset").(*kubernetes.Clientset)
 	dynamicClientSet := c.MustGet("dynamicClientSet").(*dynamic.DynamicClient)
 	sourceNamespace := c.Param("namespace")
+	// Bind into a fresh body so options from a previous request never carry over
+	var nsRequestBody NSClonerRequestBody
 	if err := c.BindJSON(&nsRequestBody); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	targetNamespace := nsRequestBody.TargetNamespace
-	if sourceNamespace == targetNamespace {
+	if targetNamespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target namespace is required"})
+		return
+	}
+	if sourceNamespace == targetNamespace && nsRequestBody.TargetCluster == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Source and target namespaces cannot be the same"})
 		return
 	}
 	//sourceNamespace := nsRequestBody.SourceNamespace
-	log.Printf("Source Namespace:%s, Target Namespace:%s\n", sourceNamespace, targetNamespace)
-	// Implement the cloneResources function
-	// Clone namespace objects
-	err := managers.CloneNamespace(clientset, dynamicClientSet, sourceNamespace, targetNamespace)
+	log.Printf("Source Namespace:%s, Target Namespace:%s, Target Cluster:%s\n", sourceNamespace, targetNamespace, nsRequestBody.TargetCluster)
+	// Jobs the client waits for are cancelled when it disconnects, background jobs only by an abort
+	ctx := context.Background()
+	if nsRequestBody.Wait || nsRequestBody.DryRun || nsRequestBody.ServerDryRun {
+		ctx = c.Request.Context()
+	}
+	job, err := managers.NewCloneJob(ctx, sourceNamespace, targetNamespace, nsRequestBody.CloneOptions)
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	if job.Options.DryRun || job.Options.Wait {
+		// Dry runs only read from the cluster, so the plan is returned right away
+		managers.RunCloneJob(clientset, dynamicClientSet, job)
+		status := job.Status()
+		if status.Phase != managers.JobPhaseSucceeded && !job.Options.DryRun {
+			c.JSON(http.StatusInternalServerError, status)
+			return
+		}
+		c.JSON(http.StatusOK, status)
+		return
+	}
+	// Clone namespace objects in the background, progress is reported through the clone job
+	go managers.RunCloneJob(clientset, dynamicClientSet, job)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":      job.ID,
+		"message": fmt.Sprintf("Cloning namespace %s to %s", sourceNamespace, targetNamespace),
+		"status":  "/api/v1/clones/" + job.ID,
+	})
+}
+
+// @Summary Get a clone job
+// @Description Get the phase, progress and result of a namespace clone job
+// @Produce json
+// @Param id path string true "Clone job ID"
+// @Success 200 {object} managers.CloneJobStatus
+// @Router /clones/:id [get]
+func GetCloneJob(c *gin.Context) {
+	status, err := managers.GetCloneJob(c.Param("id"))
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// @Summary Abort a clone job
+// @Description Cancel a running clone job. The partially cloned target namespace is removed.
+// @Produce json
+// @Param id path string true "Clone job ID"
+// @Success 202 {object} managers.CloneJobStatus
+// @Router /clones/:id/abort [post]
+func AbortCloneJob(c *gin.Context) {
+	status, err := managers.AbortCloneJob(c.Param("id"))
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	c.JSON(http.StatusAccepted, status)
+}
+
+// @Summary List clone jobs
+// @Description List all namespace clone jobs known to this server, most recent first
+// @Produce json
+// @Success 200 {array} managers.CloneJobStatus
+// @Router /clones [get]
+func ListCloneJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, managers.ListCloneJobs())
+}
+
+// @Summary Resync a cloned namespace
+// @Description Update the ConfigMaps, Secrets, ServiceAccounts and Deployments of a clone from its source namespace, keeping local overrides, and clone objects added to the source since
+// @Produce json
+// @Param namespace path string true "Cloned namespace name"
+// @Success 200 {object} managers.ResyncReport
+// @Router /namespaces/:namespace/resync [post]
+func ResyncNamespace(c *gin.Context) {
+	clientset := c.MustGet("clientset").(*kubernetes.Clientset)
+	report, err := managers.ResyncNamespace(clientset, c.Param("namespace"))
 	if err != nil {
 		c.JSON(err.Code, gin.H{"error": err.Message})
 		return
 	}
+	c.JSON(http.StatusOK, report)
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Namespace %s cloned to %s. Setting Replicas to zero. When the deployment is ", sourceNamespace, targetNamespace)})
+// @Summary Drift between a clone and its source
+// @Description Diff every cloned object against its source object: images, env, ConfigMap data, Secret data (by hash), replicas, service ports and VirtualService routes, plus objects that only exist on one side
+// @Produce json
+// @Param namespace path string true "Cloned namespace name"
+// @Success 200 {object} managers.DriftReport
+// @Router /namespaces/:namespace/drift [get]
+func GetNamespaceDrift(c *gin.Context) {
+	clientset := c.MustGet("clientset").(*kubernetes.Clientset)
+	dynamicClientSet := c.MustGet("dynamicClientSet").(*dynamic.DynamicClient)
+	report, err := managers.GetNamespaceDrift(clientset, dynamicClientSet, c.Param("namespace"))
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Extend the expiry of a cloned namespace
+// @Description Add a TTL to the cloner.io/expires-at annotation of a clone, or set a new expiry time
+// @Accept json
+// @Produce json
+// @Param namespace path string true "Cloned namespace name"
+// @Param body body ExtendRequestBody true "TTL to add or new expiry time"
+// @Success 200 {object} string
+// @Router /namespaces/:namespace/extend [post]
+func ExtendNamespaceExpiry(c *gin.Context) {
+	clientset := c.MustGet("clientset").(*kubernetes.Clientset)
+	namespace := c.Param("namespace")
+	var extendRequestBody ExtendRequestBody
+	if err := c.BindJSON(&extendRequestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if extendRequestBody.TargetCluster != "" {
+		cluster, err := managers.GetCluster(extendRequestBody.TargetCluster)
+		if err != nil {
+			c.JSON(err.Code, gin.H{"error": err.Message})
+			return
+		}
+		clientset = cluster.Clientset
+	}
+	expiresAt, err := managers.ExtendNamespaceExpiry(clientset, namespace, extendRequestBody.TTL, extendRequestBody.ExpiresAt)
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"namespace": namespace, "expiresAt": expiresAt})
+}
+
+// @Summary Delete a cloned namespace
+// @Description Delete a namespace created by the cloner in the background. Clone sources and namespaces not created by the cloner are refused.
+// @Produce json
+// @Param namespace path string true "Cloned namespace name"
+// @Param confirm query string false "Namespace name again, to confirm the deletion"
+// @Param cluster query string false "Cluster of the clone, the source cluster when empty"
+// @Success 202 {object} managers.DeletionStatus
+// @Router /namespaces/:namespace [delete]
+func DeleteNamespace(c *gin.Context) {
+	clientset := c.MustGet("clientset").(*kubernetes.Clientset)
+	dynamicClientSet := c.MustGet("dynamicClientSet").(*dynamic.DynamicClient)
+	namespace := c.Param("namespace")
+	cluster := c.Query("cluster")
+	if cluster != "" {
+		target, err := managers.GetCluster(cluster)
+		if err != nil {
+			c.JSON(err.Code, gin.H{"error": err.Message})
+			return
+		}
+		clientset = target.Clientset
+		dynamicClientSet = target.DynamicClient
+	}
+	status, err := managers.DeleteClonedNamespace(clientset, dynamicClientSet, cluster, namespace, c.Query("confirm"))
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":      status.ID,
+		"message": fmt.Sprintf("Deleting namespace %s", namespace),
+		"status":  "/api/v1/deletions/" + status.ID,
+	})
+}
+
+// @Summary Get a namespace deletion
+// @Description Get the progress of a cloned namespace deletion, including what the namespace is still terminating
+// @Produce json
+// @Param id path string true "Deletion ID"
+// @Success 200 {object} managers.DeletionStatus
+// @Router /deletions/:id [get]
+func GetDeletion(c *gin.Context) {
+	status, err := managers.GetDeletion(c.Param("id"))
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// scaleWorkload scales the Deployment or StatefulSet named by the last path parameter by delta replicas
+func scaleWorkload(c *gin.Context, kind, param string, delta int32) {
+	clientset := c.MustGet("clientset").(*kubernetes.Clientset)
+	name := c.Param(param)
+	var scaleRequestBody ScaleRequestBody
+	if err := c.BindJSON(&scaleRequestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if scaleRequestBody.Namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Namespace is required"})
+		return
+	}
+	scale, err := managers.ScaleWorkload(clientset, scaleRequestBody.Namespace, kind, name, delta, scaleRequestBody.Replicas)
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	c.JSON(http.StatusOK, scale)
+}
+
+// @Summary Scale up a cloned deployment
+// @Description Add one replica to a deployment in a cloned namespace, or scale it to replicas when set
+// @Accept json
+// @Produce json
+// @Param deployment path string true "Deployment name"
+// @Param body body ScaleRequestBody true "Namespace of the workload and optional replica count"
+// @Success 200 {object} managers.WorkloadScale
+// @Router /deployments/:deployment/scaleup [post]
+func ScaleUpDeployment(c *gin.Context) {
+	scaleWorkload(c, managers.ScaleKindDeployment, "deployment", 1)
+}
+
+// @Summary Scale down a cloned deployment
+// @Description Remove one replica from a deployment in a cloned namespace, or scale it to replicas when set
+// @Accept json
+// @Produce json
+// @Param deployment path string true "Deployment name"
+// @Param body body ScaleRequestBody true "Namespace of the workload and optional replica count"
+// @Success 200 {object} managers.WorkloadScale
+// @Router /deployments/:deployment/scaledown [post]
+func ScaleDownDeployment(c *gin.Context) {
+	scaleWorkload(c, managers.ScaleKindDeployment, "deployment", -1)
+}
+
+// @Summary Scale up a cloned statefulset
+// @Description Add one replica to a statefulset in a cloned namespace, or scale it to replicas when set
+// @Accept json
+// @Produce json
+// @Param statefulset path string true "StatefulSet name"
+// @Param body body ScaleRequestBody true "Namespace of the workload and optional replica count"
+// @Success 200 {object} managers.WorkloadScale
+// @Router /statefulsets/:statefulset/scaleup [post]
+func ScaleUpStatefulSet(c *gin.Context) {
+	scaleWorkload(c, managers.ScaleKindStatefulSet, "statefulset", 1)
+}
+
+// @Summary Scale down a cloned statefulset
+// @Description Remove one replica from a statefulset in a cloned namespace, or scale it to replicas when set
+// @Accept json
+// @Produce json
+// @Param statefulset path string true "StatefulSet name"
+// @Param body body ScaleRequestBody true "Namespace of the workload and optional replica count"
+// @Success 200 {object} managers.WorkloadScale
+// @Router /statefulsets/:statefulset/scaledown [post]
+func ScaleDownStatefulSet(c *gin.Context) {
+	scaleWorkload(c, managers.ScaleKindStatefulSet, "statefulset", -1)
+}
+
+// @Summary Scale a cloned namespace
+// @Description Set every deployment and statefulset of a cloned namespace to replicas, or restore the replica counts of the source namespace
+// @Accept json
+// @Produce json
+// @Param namespace path string true "Cloned namespace name"
+// @Param body body NamespaceScaleRequestBody true "Replica count or restore"
+// @Success 200 {array} managers.WorkloadScale
+// @Router /namespaces/:namespace/scale [post]
+func ScaleNamespace(c *gin.Context) {
+	clientset := c.MustGet("clientset").(*kubernetes.Clientset)
+	var scaleRequestBody NamespaceScaleRequestBody
+	if err := c.BindJSON(&scaleRequestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scales, err := managers.ScaleNamespace(clientset, c.Param("namespace"), scaleRequestBody.Replicas, scaleRequestBody.Restore)
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	c.JSON(http.StatusOK, scales)
+}
+
+// @Summary Wake a clone created with zero replicas
+// @Description Restore the source replica count of the deployments and statefulsets of a clone created with zeroReplicas
+// @Produce json
+// @Param namespace path string true "Cloned namespace name"
+// @Param name query []string false "Only wake these workloads"
+// @Success 200 {array} managers.WorkloadScale
+// @Router /namespaces/:namespace/wake [post]
+func WakeNamespace(c *gin.Context) {
+	clientset := c.MustGet("clientset").(*kubernetes.Clientset)
+	scales, err := managers.WakeNamespace(clientset, c.Param("namespace"), c.QueryArray("name"))
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	c.JSON(http.StatusOK, scales)
+}
+
+// @Summary List target clusters
+// @Description List the clusters, by kubeconfig context name, that namespaces can be cloned into
+// @Produce json
+// @Success 200 {array} string
+// @Router /clusters [get]
+func ListClusters(c *gin.Context) {
+	c.JSON(http.StatusOK, managers.ListClusters())
 }
 
 // @Summary Display deployments for a specific namespace
@@ -255,3 +588,44 @@ func UpdateConfigMap(c *gin.Context) {
 	c.JSON(http.StatusOK, "Patched ConfigMap: "+configMapName)
 
 }
+
+// @Summary Mirror source traffic to a clone
+// @Description Add an Istio mirror to the routes of the source VirtualServices of a clone, sending a copy of the requests to the cloned services. Responses from the clone are discarded.
+// @Produce json
+// @Param namespace path string true "Cloned namespace name"
+// @Param request body MirrorRequestBody true "VirtualServices, routes and percentage to mirror, {} mirrors everything"
+// @Success 200 {array} managers.MirroredRoute
+// @Router /namespaces/:namespace/mirror [post]
+func MirrorToClone(c *gin.Context) {
+	clientset := c.MustGet("clientset").(*kubernetes.Clientset)
+	dynamicClientSet := c.MustGet("dynamicClientSet").(*dynamic.DynamicClient)
+	var mirrorRequestBody MirrorRequestBody
+	if err := c.BindJSON(&mirrorRequestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	mirrored, err := managers.MirrorToClone(clientset, dynamicClientSet, c.Param("namespace"), mirrorRequestBody.VirtualServices, mirrorRequestBody.Routes, mirrorRequestBody.Percentage)
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	c.JSON(http.StatusOK, mirrored)
+}
+
+// @Summary Stop mirroring source traffic to a clone
+// @Description Remove the mirrors to a clone from its source VirtualServices
+// @Produce json
+// @Param namespace path string true "Cloned namespace name"
+// @Param virtualService query []string false "Only these source or cloned VirtualServices"
+// @Success 200 {array} managers.MirroredRoute
+// @Router /namespaces/:namespace/mirror [delete]
+func StopMirrorToClone(c *gin.Context) {
+	clientset := c.MustGet("clientset").(*kubernetes.Clientset)
+	dynamicClientSet := c.MustGet("dynamicClientSet").(*dynamic.DynamicClient)
+	removed, err := managers.StopMirrorToClone(clientset, dynamicClientSet, c.Param("namespace"), c.QueryArray("virtualService"))
+	if err != nil {
+		c.JSON(err.Code, gin.H{"error": err.Message})
+		return
+	}
+	c.JSON(http.StatusOK, removed)
+}
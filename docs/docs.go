@@ -15,6 +15,98 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/clones": {
+            "get": {
+                "description": "List all namespace clone jobs known to this server, most recent first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List clone jobs",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/managers.CloneJobStatus"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/clones/:id": {
+            "get": {
+                "description": "Get the phase, progress and result of a namespace clone job",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get a clone job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clone job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/managers.CloneJobStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/clones/:id/abort": {
+            "post": {
+                "description": "Cancel a running clone job. The partially cloned target namespace is removed.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Abort a clone job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clone job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/managers.CloneJobStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/clusters": {
+            "get": {
+                "description": "List the clusters, by kubeconfig context name, that namespaces can be cloned into",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List target clusters",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/configmaps/:configmap": {
             "post": {
                 "description": "Update a config map in a specific namespace",
@@ -46,6 +138,32 @@ const docTemplate = `{
                 }
             }
         },
+        "/deletions/:id": {
+            "get": {
+                "description": "Get the progress of a cloned namespace deletion, including what the namespace is still terminating",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get a namespace deletion",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deletion ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/managers.DeletionStatus"
+                        }
+                    }
+                }
+            }
+        },
         "/deployments/:deployment": {
             "post": {
                 "description": "Update the image of a deployment in a specific namespace",
@@ -77,6 +195,82 @@ const docTemplate = `{
                 }
             }
         },
+        "/deployments/:deployment/scaledown": {
+            "post": {
+                "description": "Remove one replica from a deployment in a cloned namespace, or scale it to replicas when set",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Scale down a cloned deployment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment name",
+                        "name": "deployment",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Namespace of the workload and optional replica count",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ScaleRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/managers.WorkloadScale"
+                        }
+                    }
+                }
+            }
+        },
+        "/deployments/:deployment/scaleup": {
+            "post": {
+                "description": "Add one replica to a deployment in a cloned namespace, or scale it to replicas when set",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Scale up a cloned deployment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment name",
+                        "name": "deployment",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Namespace of the workload and optional replica count",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ScaleRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/managers.WorkloadScale"
+                        }
+                    }
+                }
+            }
+        },
         "/namespaces": {
             "get": {
                 "description": "Get all namespaces in the cluster",
@@ -97,9 +291,47 @@ const docTemplate = `{
                 }
             }
         },
+        "/namespaces/:namespace": {
+            "delete": {
+                "description": "Delete a namespace created by the cloner in the background. Clone sources and namespaces not created by the cloner are refused.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete a cloned namespace",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cloned namespace name",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Namespace name again, to confirm the deletion",
+                        "name": "confirm",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cluster of the clone, the source cluster when empty",
+                        "name": "cluster",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/managers.DeletionStatus"
+                        }
+                    }
+                }
+            }
+        },
         "/namespaces/:namespace/cloneNamespace": {
             "post": {
-                "description": "Clone a namespace and its objects to a new namespace",
+                "description": "Start an asynchronous job cloning a namespace and its objects to a new namespace.\nWith dryRun set, the clone is planned synchronously and the plan is returned instead.",
                 "consumes": [
                     "application/json"
                 ],
@@ -108,6 +340,13 @@ const docTemplate = `{
                 ],
                 "summary": "Clone a namespace",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Source namespace name",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "description": "Namespace clone request body",
                         "name": "body",
@@ -121,6 +360,12 @@ const docTemplate = `{
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/managers.CloneJobStatus"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
                             "type": "string"
                         }
@@ -209,17 +454,17 @@ const docTemplate = `{
                 }
             }
         },
-        "/namespaces/:namespace/secrets/display": {
+        "/namespaces/:namespace/drift": {
             "get": {
-                "description": "Display all secrets in the specified namespace",
+                "description": "Diff every cloned object against its source object: images, env, ConfigMap data, Secret data (by hash), replicas, service ports and VirtualService routes, plus objects that only exist on one side",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Display secrets for a specific namespace",
+                "summary": "Drift between a clone and its source",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Namespace name",
+                        "description": "Cloned namespace name",
                         "name": "namespace",
                         "in": "path",
                         "required": true
@@ -229,39 +474,72 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/managers.DriftReport"
                         }
                     }
                 }
             }
         },
-        "/secrets/:secret": {
+        "/namespaces/:namespace/extend": {
             "post": {
-                "description": "Update a secret in a specific namespace",
+                "description": "Add a TTL to the cloner.io/expires-at annotation of a clone, or set a new expiry time",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Update a secret",
+                "summary": "Extend the expiry of a cloned namespace",
                 "parameters": [
                     {
-                        "description": "Secret Update Request Body",
+                        "type": "string",
+                        "description": "Cloned namespace name",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "TTL to add or new expiry time",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controllers.SecretPatchRequestBody"
+                            "$ref": "#/definitions/controllers.ExtendRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
                         }
+                    }
+                }
+            }
+        },
+        "/namespaces/:namespace/mirror": {
+            "post": {
+                "description": "Add an Istio mirror to the routes of the source VirtualServices of a clone, sending a copy of the requests to the cloned services. Responses from the clone are discarded.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Mirror source traffic to a clone",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cloned namespace name",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "description": "Secret patch request body",
-                        "name": "secretPatchRequestBody",
+                        "description": "VirtualServices, routes and percentage to mirror, {} mirrors everything",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controllers.SecretPatchRequestBody"
+                            "$ref": "#/definitions/controllers.MirrorRequestBody"
                         }
                     }
                 ],
@@ -269,16 +547,304 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/managers.MirroredRoute"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove the mirrors to a clone from its source VirtualServices",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Stop mirroring source traffic to a clone",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cloned namespace name",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
                             "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Only these source or cloned VirtualServices",
+                        "name": "virtualService",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/managers.MirroredRoute"
+                            }
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controllers.ConfigMapPatchRequestBody": {
-            "type": "object",
+        },
+        "/namespaces/:namespace/resync": {
+            "post": {
+                "description": "Update the ConfigMaps, Secrets, ServiceAccounts and Deployments of a clone from its source namespace, keeping local overrides, and clone objects added to the source since",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Resync a cloned namespace",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cloned namespace name",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/managers.ResyncReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/namespaces/:namespace/scale": {
+            "post": {
+                "description": "Set every deployment and statefulset of a cloned namespace to replicas, or restore the replica counts of the source namespace",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Scale a cloned namespace",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cloned namespace name",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Replica count or restore",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.NamespaceScaleRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/managers.WorkloadScale"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/namespaces/:namespace/secrets/display": {
+            "get": {
+                "description": "Display all secrets in the specified namespace",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Display secrets for a specific namespace",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Namespace name",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/namespaces/:namespace/wake": {
+            "post": {
+                "description": "Restore the source replica count of the deployments and statefulsets of a clone created with zeroReplicas",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Wake a clone created with zero replicas",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cloned namespace name",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Only wake these workloads",
+                        "name": "name",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/managers.WorkloadScale"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/secrets/:secret": {
+            "post": {
+                "description": "Update a secret in a specific namespace",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update a secret",
+                "parameters": [
+                    {
+                        "description": "Secret Update Request Body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SecretPatchRequestBody"
+                        }
+                    },
+                    {
+                        "description": "Secret patch request body",
+                        "name": "secretPatchRequestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SecretPatchRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/statefulsets/:statefulset/scaledown": {
+            "post": {
+                "description": "Remove one replica from a statefulset in a cloned namespace, or scale it to replicas when set",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Scale down a cloned statefulset",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "StatefulSet name",
+                        "name": "statefulset",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Namespace of the workload and optional replica count",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ScaleRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/managers.WorkloadScale"
+                        }
+                    }
+                }
+            }
+        },
+        "/statefulsets/:statefulset/scaleup": {
+            "post": {
+                "description": "Add one replica to a statefulset in a cloned namespace, or scale it to replicas when set",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Scale up a cloned statefulset",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "StatefulSet name",
+                        "name": "statefulset",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Namespace of the workload and optional replica count",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ScaleRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/managers.WorkloadScale"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "controllers.ConfigMapPatchRequestBody": {
+            "type": "object",
             "properties": {
                 "data": {
                     "type": "object",
@@ -306,26 +872,900 @@ const docTemplate = `{
                 }
             }
         },
-        "controllers.NSClonerRequestBody": {
+        "controllers.ExtendRequestBody": {
             "type": "object",
             "properties": {
-                "targetNamespace": {
-                    "description": "SourceNamespace string ` + "`" + `json:\"sourceNamespace\"` + "`" + `",
+                "expiresAt": {
+                    "description": "Replaces the current expiry",
+                    "type": "string"
+                },
+                "targetCluster": {
+                    "description": "Cluster of the clone, the source cluster when empty",
+                    "type": "string"
+                },
+                "ttl": {
+                    "description": "Added to the current expiry, e.g. \"24h\"",
                     "type": "string"
                 }
             }
         },
-        "controllers.SecretPatchRequestBody": {
+        "controllers.MirrorRequestBody": {
             "type": "object",
             "properties": {
-                "data": {
-                    "type": "object",
-                    "additionalProperties": true
+                "percentage": {
+                    "description": "Percentage of requests to mirror, 100 when not set",
+                    "type": "number"
                 },
-                "namespace": {
-                    "type": "string"
+                "routes": {
+                    "description": "Names of the http routes to mirror, all routes when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "virtualServices": {
+                    "description": "Source or cloned VirtualServices to mirror, all cloned VirtualServices when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
+        },
+        "controllers.NSClonerRequestBody": {
+            "type": "object",
+            "properties": {
+                "cloneAllResources": {
+                    "description": "Also clone every other namespaced resource type found through discovery",
+                    "type": "boolean"
+                },
+                "cloneClusterRoleBindings": {
+                    "description": "Bind the ClusterRoles that ClusterRoleBindings grant to ServiceAccounts of the source namespace to the\ncloned ServiceAccounts, with RoleBindings in the target namespace",
+                    "type": "boolean"
+                },
+                "denyResources": {
+                    "description": "Extra resources (\"resource\" or \"resource.group\") to leave out of CloneAllResources",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "dryRun": {
+                    "description": "Plan the clone without creating anything in the target namespace",
+                    "type": "boolean"
+                },
+                "excludeKinds": {
+                    "description": "Never clone objects of these kinds, e.g. [\"Job\", \"CronJob\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "expiresAt": {
+                    "description": "Delete the clone at this time (RFC 3339), instead of a TTL",
+                    "type": "string"
+                },
+                "headerRouting": {
+                    "description": "Route requests carrying a header from the source VirtualServices to the clone, e.g. {\"header\": \"x-clone\"}.\nThe routes are removed when the clone is deleted.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/managers.HeaderRouting"
+                        }
+                    ]
+                },
+                "hosts": {
+                    "description": "Rewrite the hosts of Ingresses and VirtualServices, by default they are prefixed with \"\u003ctarget\u003e-\"",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/managers.HostRewrite"
+                        }
+                    ]
+                },
+                "images": {
+                    "description": "Rewrite container images of Deployments, StatefulSets, Jobs and CronJobs while cloning",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/managers.ImageOverrides"
+                        }
+                    ]
+                },
+                "includeKinds": {
+                    "description": "Only clone objects of these kinds, e.g. [\"Deployment\", \"Service\"]. All kinds when empty.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "labelSelector": {
+                    "description": "Only clone objects matching this label selector, e.g. \"tier=frontend\"",
+                    "type": "string"
+                },
+                "nameRegex": {
+                    "description": "Only clone objects whose name matches this regular expression",
+                    "type": "string"
+                },
+                "networkIsolation": {
+                    "description": "Add a NetworkPolicy that keeps the clone from calling the source and other production namespaces",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/managers.NetworkIsolation"
+                        }
+                    ]
+                },
+                "quotaProfile": {
+                    "description": "Apply a quota profile loaded with -quota-profiles, e.g. \"small\", instead of the source's ResourceQuotas\nand LimitRanges",
+                    "type": "string"
+                },
+                "readiness": {
+                    "description": "How long to wait for created workloads and services: \"none\", \"created\" or \"ready\" (the default)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/managers.ReadinessPolicy"
+                        }
+                    ]
+                },
+                "readinessTimeouts": {
+                    "description": "Per kind readiness timeouts, e.g. {\"Deployment\": \"5m\"}. Defaults to DefaultReadinessTimeouts.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "secrets": {
+                    "description": "Copy, skip, regenerate or substitute Secrets by type and name, e.g.\n{\"rules\": [{\"type\": \"Opaque\", \"name\": \"db-.*\", \"action\": \"regenerate\"}]}",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/managers.SecretPolicy"
+                        }
+                    ]
+                },
+                "serverDryRun": {
+                    "description": "Validate every planned object with a server side dry run (CreateOptions{DryRun: All}). Implies DryRun.",
+                    "type": "boolean"
+                },
+                "statefulSets": {
+                    "description": "Override the replicas of cloned StatefulSets and the storage class of their volumeClaimTemplates",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/managers.StatefulSetOverrides"
+                        }
+                    ]
+                },
+                "targetCluster": {
+                    "description": "Clone into another cluster, named by its kubeconfig context. The source cluster when empty.",
+                    "type": "string"
+                },
+                "targetNamespace": {
+                    "description": "SourceNamespace string ` + "`" + `json:\"sourceNamespace\"` + "`" + `",
+                    "type": "string"
+                },
+                "ttl": {
+                    "description": "Delete the clone after this duration, e.g. \"72h\". Stored in the cloner.io/expires-at namespace annotation.",
+                    "type": "string"
+                },
+                "virtualServices": {
+                    "description": "Rewrite VirtualService destinations, gateways and exportTo, by default they move to the target namespace",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/managers.VirtualServicePolicy"
+                        }
+                    ]
+                },
+                "volumes": {
+                    "description": "How PersistentVolumeClaims are cloned, e.g. {\"strategy\": \"snapshot\"}. Claims get new, empty volumes by default.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/managers.VolumeCloning"
+                        }
+                    ]
+                },
+                "wait": {
+                    "description": "Run the clone while the request waits, cancelling it when the client disconnects",
+                    "type": "boolean"
+                },
+                "zeroReplicas": {
+                    "description": "Create Deployments and StatefulSets with zero replicas, the source count is restored by a wake",
+                    "type": "boolean"
+                }
+            }
+        },
+        "controllers.NamespaceScaleRequestBody": {
+            "type": "object",
+            "properties": {
+                "replicas": {
+                    "description": "Set every workload to this count",
+                    "type": "integer"
+                },
+                "restore": {
+                    "description": "Set every workload back to the replica count of its source workload",
+                    "type": "boolean"
+                }
+            }
+        },
+        "controllers.ScaleRequestBody": {
+            "type": "object",
+            "properties": {
+                "namespace": {
+                    "type": "string"
+                },
+                "replicas": {
+                    "description": "Scale to this count instead of by one replica",
+                    "type": "integer"
+                }
+            }
+        },
+        "controllers.SecretPatchRequestBody": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "namespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "managers.CloneJobStatus": {
+            "type": "object",
+            "properties": {
+                "currentKind": {
+                    "type": "string"
+                },
+                "endTime": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "objects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.ObjectProgress"
+                    }
+                },
+                "phase": {
+                    "$ref": "#/definitions/managers.JobPhase"
+                },
+                "plan": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.PlannedObject"
+                    }
+                },
+                "rewrites": {
+                    "description": "Every field rewritten to point at the clone instead of the source",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.FieldRewrite"
+                    }
+                },
+                "secrets": {
+                    "description": "What the secret policy did with each Secret",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.SecretOutcome"
+                    }
+                },
+                "sourceNamespace": {
+                    "type": "string"
+                },
+                "startTime": {
+                    "type": "string"
+                },
+                "targetNamespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "managers.DeletionStatus": {
+            "type": "object",
+            "properties": {
+                "cluster": {
+                    "type": "string"
+                },
+                "endTime": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message": {
+                    "description": "What the namespace is still waiting for, from its status conditions",
+                    "type": "string"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "namespacePhase": {
+                    "description": "Phase of the namespace itself while it terminates, e.g. \"Terminating\"",
+                    "type": "string"
+                },
+                "phase": {
+                    "$ref": "#/definitions/managers.JobPhase"
+                },
+                "startTime": {
+                    "type": "string"
+                }
+            }
+        },
+        "managers.DriftReport": {
+            "type": "object",
+            "properties": {
+                "drifted": {
+                    "description": "Cloned objects that differ from their source object",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.ObjectDrift"
+                    }
+                },
+                "onlyInSource": {
+                    "description": "Source objects without a clone",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.ObjectRef"
+                    }
+                },
+                "onlyInTarget": {
+                    "description": "Objects in the clone without a source object, created locally or deleted from the source",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.ObjectRef"
+                    }
+                },
+                "sourceNamespace": {
+                    "type": "string"
+                },
+                "targetNamespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "managers.FieldDiff": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "source": {},
+                "target": {}
+            }
+        },
+        "managers.FieldRewrite": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "managers.HeaderRouting": {
+            "type": "object",
+            "properties": {
+                "header": {
+                    "description": "Header to match, \"x-clone\" by default",
+                    "type": "string"
+                },
+                "value": {
+                    "description": "Exact header value to match, the target namespace by default",
+                    "type": "string"
+                }
+            }
+        },
+        "managers.HostRewrite": {
+            "type": "object",
+            "properties": {
+                "copyTLSSecrets": {
+                    "description": "Copy the TLS secrets referenced by cloned Ingresses when the secret step did not clone them",
+                    "type": "boolean"
+                },
+                "domain": {
+                    "description": "Domain used by Template, e.g. \"dev.example.com\". Defaults to the host without its first label.",
+                    "type": "string"
+                },
+                "rules": {
+                    "description": "Regular expression rules matched against the whole host",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.HostRewriteRule"
+                    }
+                },
+                "template": {
+                    "description": "Go template for the new host, e.g. \"{{.Target}}.{{.Domain}}\". Fields are Host, Subdomain\n(the first label of the host), Domain, Source and Target. Defaults to \"{{.Target}}-{{.Host}}\".",
+                    "type": "string"
+                }
+            }
+        },
+        "managers.HostRewriteRule": {
+            "type": "object",
+            "properties": {
+                "pattern": {
+                    "description": "Regular expression matched against the whole host, e.g. \"(.*)\\\\.example\\\\.com\"",
+                    "type": "string"
+                },
+                "replacement": {
+                    "description": "Replacement host, may reference groups from Pattern, e.g. \"$1.dev.example.com\"",
+                    "type": "string"
+                }
+            }
+        },
+        "managers.ImageOverrides": {
+            "type": "object",
+            "properties": {
+                "containers": {
+                    "description": "Container name to image, applied to every workload",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "repositories": {
+                    "description": "Rules matched in order against the image repository (the image without tag or digest)",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.RepositoryOverride"
+                    }
+                },
+                "workloads": {
+                    "description": "\"\u003cworkload\u003e/\u003ccontainer\u003e\" to image, e.g. \"frontend/app\"",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "managers.JobPhase": {
+            "type": "string",
+            "enum": [
+                "Pending",
+                "Running",
+                "Succeeded",
+                "Failed",
+                "Aborted"
+            ],
+            "x-enum-varnames": [
+                "JobPhasePending",
+                "JobPhaseRunning",
+                "JobPhaseSucceeded",
+                "JobPhaseFailed",
+                "JobPhaseAborted"
+            ]
+        },
+        "managers.MirroredRoute": {
+            "type": "object",
+            "properties": {
+                "host": {
+                    "type": "string"
+                },
+                "percentage": {
+                    "type": "number"
+                },
+                "route": {
+                    "type": "string"
+                },
+                "skipped": {
+                    "description": "Why the route isn't mirrored",
+                    "type": "string"
+                },
+                "virtualService": {
+                    "type": "string"
+                }
+            }
+        },
+        "managers.NetworkIsolation": {
+            "type": "object",
+            "properties": {
+                "allowedCIDRs": {
+                    "description": "Destinations outside of the cluster the clone may call, none by default. Some network plugins,\ne.g. Calico and Cilium, also match pod IPs against these, so a range covering the pod network lets\ntraffic to production namespaces through.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "namespaceSelector": {
+                    "description": "Production namespaces by label selector, e.g. \"env=production\", resolved when the clone is created",
+                    "type": "string"
+                },
+                "namespaces": {
+                    "description": "Production namespaces by name",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "managers.ObjectDrift": {
+            "type": "object",
+            "properties": {
+                "differences": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.FieldDiff"
+                    }
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "sourceName": {
+                    "type": "string"
+                }
+            }
+        },
+        "managers.ObjectProgress": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "managers.ObjectRef": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "managers.PVCStrategy": {
+            "type": "string",
+            "enum": [
+                "empty",
+                "clone",
+                "snapshot"
+            ],
+            "x-enum-varnames": [
+                "PVCStrategyEmpty",
+                "PVCStrategyClone",
+                "PVCStrategySnapshot"
+            ]
+        },
+        "managers.PlannedObject": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "annotations": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "mutations": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "name": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "serverDryRun": {
+                    "type": "string"
+                },
+                "serverDryRunMessage": {
+                    "type": "string"
+                }
+            }
+        },
+        "managers.ReadinessPolicy": {
+            "type": "string",
+            "enum": [
+                "none",
+                "created",
+                "ready"
+            ],
+            "x-enum-varnames": [
+                "ReadinessNone",
+                "ReadinessCreated",
+                "ReadinessReady"
+            ]
+        },
+        "managers.RepositoryOverride": {
+            "type": "object",
+            "properties": {
+                "pattern": {
+                    "description": "Regular expression matched against the whole repository, e.g. \"registry.prod.io/(.*)\"",
+                    "type": "string"
+                },
+                "repository": {
+                    "description": "Replacement repository, may reference groups from Pattern, e.g. \"registry.dev.io/$1\". The tag is kept.",
+                    "type": "string"
+                },
+                "tag": {
+                    "description": "Replacement tag",
+                    "type": "string"
+                }
+            }
+        },
+        "managers.ResyncChange": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "changes": {
+                    "description": "Changed fields, e.g. \"updated data.LOG_LEVEL\". Secret values are never reported.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "preserved": {
+                    "description": "Locally overridden fields that were kept",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "reason": {
+                    "description": "Why the object was skipped",
+                    "type": "string"
+                }
+            }
+        },
+        "managers.ResyncReport": {
+            "type": "object",
+            "properties": {
+                "objects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.ResyncChange"
+                    }
+                },
+                "sourceNamespace": {
+                    "type": "string"
+                },
+                "syncedAt": {
+                    "type": "string"
+                },
+                "targetNamespace": {
+                    "type": "string"
+                }
+            }
+        },
+        "managers.SecretAction": {
+            "type": "string",
+            "enum": [
+                "copy",
+                "skip",
+                "regenerate",
+                "substitute"
+            ],
+            "x-enum-varnames": [
+                "SecretActionCopy",
+                "SecretActionSkip",
+                "SecretActionRegenerate",
+                "SecretActionSubstitute"
+            ]
+        },
+        "managers.SecretOutcome": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/managers.SecretAction"
+                },
+                "keys": {
+                    "description": "Regenerated or substituted keys",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/v1.SecretType"
+                }
+            }
+        },
+        "managers.SecretPolicy": {
+            "type": "object",
+            "properties": {
+                "rules": {
+                    "description": "Rules checked in order after MandatorySecretRules and before DefaultSecretRules, the first matching rule\napplies. Secrets no rule matches are copied.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/managers.SecretRule"
+                    }
+                },
+                "values": {
+                    "description": "Values of substituted keys, by Secret name and key",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "object",
+                        "additionalProperties": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "managers.SecretRule": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/managers.SecretAction"
+                },
+                "keys": {
+                    "description": "Keys regenerated or substituted, every key when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "description": "Regular expression the whole Secret name must match. Every name when empty.",
+                    "type": "string"
+                },
+                "owner": {
+                    "description": "Owner of the Secret as \"apiVersion/kind\", e.g. \"kube-green.com/v1alpha1/SleepInfo\"",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Why secrets are skipped, shown in the clone report",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Secret type, e.g. \"kubernetes.io/tls\". Every type when empty.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/v1.SecretType"
+                        }
+                    ]
+                }
+            }
+        },
+        "managers.StatefulSetOverrides": {
+            "type": "object",
+            "properties": {
+                "replicas": {
+                    "description": "Replicas of every cloned StatefulSet, the source count when not set",
+                    "type": "integer"
+                },
+                "storageClass": {
+                    "description": "StorageClass of the volumeClaimTemplates and of the empty claims cloned for them",
+                    "type": "string"
+                }
+            }
+        },
+        "managers.VirtualServicePolicy": {
+            "type": "object",
+            "properties": {
+                "destinations": {
+                    "description": "Route destination and mirror hosts qualified with the source namespace: \"target\" or \"keep\"",
+                    "type": "string"
+                },
+                "exportTo": {
+                    "description": "The source namespace in exportTo: \"target\", \"keep\" or \"private\" to export to \".\" only",
+                    "type": "string"
+                },
+                "gatewayMap": {
+                    "description": "Gateways to replace, e.g. {\"istio-system/public\": \"istio-system/dev\"}. Applied before Gateways.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "gateways": {
+                    "description": "Gateways referenced as \"source/gateway\" or by their source service host: \"target\" or \"keep\"",
+                    "type": "string"
+                }
+            }
+        },
+        "managers.VolumeCloning": {
+            "type": "object",
+            "properties": {
+                "claims": {
+                    "description": "Per claim strategies, by claim name, overriding Strategy",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/managers.PVCStrategy"
+                    }
+                },
+                "strategy": {
+                    "description": "Strategy for every claim, \"empty\" when not set",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/managers.PVCStrategy"
+                        }
+                    ]
+                },
+                "volumeSnapshotClass": {
+                    "description": "VolumeSnapshotClass of the snapshots taken with the snapshot strategy, the cluster default when empty",
+                    "type": "string"
+                }
+            }
+        },
+        "managers.WorkloadScale": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "integer"
+                }
+            }
+        },
+        "v1.SecretType": {
+            "type": "string",
+            "enum": [
+                "Opaque",
+                "kubernetes.io/service-account-token",
+                "kubernetes.io/dockercfg",
+                "kubernetes.io/dockerconfigjson",
+                "kubernetes.io/basic-auth",
+                "kubernetes.io/ssh-auth",
+                "kubernetes.io/tls",
+                "bootstrap.kubernetes.io/token"
+            ],
+            "x-enum-varnames": [
+                "SecretTypeOpaque",
+                "SecretTypeServiceAccountToken",
+                "SecretTypeDockercfg",
+                "SecretTypeDockerConfigJson",
+                "SecretTypeBasicAuth",
+                "SecretTypeSSHAuth",
+                "SecretTypeTLS",
+                "SecretTypeBootstrapToken"
+            ]
         }
     }
 }`
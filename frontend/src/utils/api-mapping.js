@@ -51,6 +51,9 @@ const apiMapping = {
                 requestObject = {
                     method: 'POST',
                     url: `${backendHost}/api/v1/deployments/${deployment.name}/scaleup`,
+                    data: {
+                        namespace: deployment.namespace,
+                    }
                 };
                 break;
             }
@@ -4,9 +4,12 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/venkatvghub/k8s-namespace-cloner/docs"
+	"github.com/venkatvghub/k8s-namespace-cloner/managers"
 	"github.com/venkatvghub/k8s-namespace-cloner/router"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
@@ -30,7 +33,25 @@ func main() {
 	//kubeconfigPath := flag.String("kubeconfig", "", "Path to kubeconfig file")
 	// Define and parse the command line flag
 	production := flag.Bool("production", false, "Start server in production mode")
+	clustersKubeconfig := flag.String("clusters-kubeconfig", "", "Kubeconfig whose contexts are registered as clone target clusters. Defaults to ~/.kube/config when not running in cluster")
+	genericDenyList := flag.String("generic-deny-list", "", "Comma separated resources (resource or resource.group) never cloned by cloneAllResources, in addition to the default list")
+	replaceGenericDenyList := flag.Bool("replace-generic-deny-list", false, "Use -generic-deny-list instead of the default list rather than in addition to it")
+	reaperInterval := flag.Duration("reaper-interval", 5*time.Minute, "How often expired clones are deleted, 0 disables the reaper")
+	quotaProfilesPath := flag.String("quota-profiles", "", "YAML or JSON file of named ResourceQuota and LimitRange profiles clone requests can apply with quotaProfile")
+	expiryWarning := flag.Duration("expiry-warning", time.Hour, "How long before expiry a warning event is recorded on a clone")
+	jobRetention := flag.Duration("job-retention", managers.JobRetention, "How long finished clone and deletion jobs are kept for status requests")
 	flag.Parse()
+	managers.JobRetention = *jobRetention
+
+	if *genericDenyList != "" {
+		managers.SetGenericClonerDenyList(strings.Split(*genericDenyList, ","), *replaceGenericDenyList)
+	}
+
+	if *quotaProfilesPath != "" {
+		if err := managers.LoadQuotaProfiles(*quotaProfilesPath); err != nil {
+			panic(fmt.Sprintf("Error loading quota profiles: %v", err))
+		}
+	}
 
 	// Initialize Kubernetes client based on the command line argument
 	var config *rest.Config
@@ -67,6 +88,21 @@ func main() {
 		panic(fmt.Sprintf("Error creating Kubernetes client: %v", err))
 	}
 
+	// Register target clusters for cross cluster clones
+	if *clustersKubeconfig == "" && !*inCluster {
+		*clustersKubeconfig = getKubeConfigPath()
+	}
+	if *clustersKubeconfig != "" {
+		if err := managers.LoadClusterRegistry(*clustersKubeconfig); err != nil {
+			log.Printf("Cross cluster clones are disabled: %v\n", err)
+		}
+	}
+
+	// Delete clones past their cloner.io/expires-at time
+	if *reaperInterval > 0 {
+		managers.StartReaper(clientset, dynamicClient, *reaperInterval, *expiryWarning)
+	}
+
 	r := router.InitializeRoutes(clientset, dynamicClient)
 	log.Printf("Startng server at port 8080\n")
 	r.Run(":8080")
@@ -0,0 +1,264 @@
+package managers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CloneOptions control how CloneNamespace clones a namespace. They are set once when the
+// clone job is created and are read-only afterwards.
+type CloneOptions struct {
+	// Plan the clone without creating anything in the target namespace
+	DryRun bool `json:"dryRun"`
+	// Validate every planned object with a server side dry run (CreateOptions{DryRun: All}). Implies DryRun.
+	ServerDryRun bool `json:"serverDryRun"`
+	// Also clone every other namespaced resource type found through discovery
+	CloneAllResources bool `json:"cloneAllResources"`
+	// Extra resources ("resource" or "resource.group") to leave out of CloneAllResources
+	DenyResources []string `json:"denyResources"`
+
+	// Only clone objects of these kinds, e.g. ["Deployment", "Service"]. All kinds when empty.
+	IncludeKinds []string `json:"includeKinds"`
+	// Never clone objects of these kinds, e.g. ["Job", "CronJob"]
+	ExcludeKinds []string `json:"excludeKinds"`
+	// Only clone objects matching this label selector, e.g. "tier=frontend"
+	LabelSelector string `json:"labelSelector"`
+	// Only clone objects whose name matches this regular expression
+	NameRegex string `json:"nameRegex"`
+
+	// Create Deployments and StatefulSets with zero replicas, the source count is restored by a wake
+	ZeroReplicas bool `json:"zeroReplicas"`
+
+	// How long to wait for created workloads and services: "none", "created" or "ready" (the default)
+	Readiness ReadinessPolicy `json:"readiness"`
+	// Per kind readiness timeouts, e.g. {"Deployment": "5m"}. Defaults to DefaultReadinessTimeouts.
+	ReadinessTimeouts map[string]string `json:"readinessTimeouts"`
+	// Run the clone while the request waits, cancelling it when the client disconnects
+	Wait bool `json:"wait"`
+
+	// Rewrite container images of Deployments, StatefulSets, Jobs and CronJobs while cloning
+	Images *ImageOverrides `json:"images"`
+	// Rewrite the hosts of Ingresses and VirtualServices, by default they are prefixed with "<target>-"
+	Hosts *HostRewrite `json:"hosts"`
+	// Rewrite VirtualService destinations, gateways and exportTo, by default they move to the target namespace
+	VirtualServices *VirtualServicePolicy `json:"virtualServices"`
+	// Route requests carrying a header from the source VirtualServices to the clone, e.g. {"header": "x-clone"}.
+	// The routes are removed when the clone is deleted.
+	HeaderRouting *HeaderRouting `json:"headerRouting"`
+	// Bind the ClusterRoles that ClusterRoleBindings grant to ServiceAccounts of the source namespace to the
+	// cloned ServiceAccounts, with RoleBindings in the target namespace
+	CloneClusterRoleBindings bool `json:"cloneClusterRoleBindings"`
+	// Add a NetworkPolicy that keeps the clone from calling the source and other production namespaces
+	NetworkIsolation *NetworkIsolation `json:"networkIsolation"`
+	// Apply a quota profile loaded with -quota-profiles, e.g. "small", instead of the source's ResourceQuotas
+	// and LimitRanges
+	QuotaProfile string `json:"quotaProfile"`
+	// How PersistentVolumeClaims are cloned, e.g. {"strategy": "snapshot"}. Claims get new, empty volumes by default.
+	Volumes *VolumeCloning `json:"volumes"`
+	// Override the replicas of cloned StatefulSets and the storage class of their volumeClaimTemplates
+	StatefulSets *StatefulSetOverrides `json:"statefulSets"`
+	// Copy, skip, regenerate or substitute Secrets by type and name, e.g.
+	// {"rules": [{"type": "Opaque", "name": "db-.*", "action": "regenerate"}]}
+	Secrets *SecretPolicy `json:"secrets"`
+
+	// Clone into another cluster, named by its kubeconfig context. The source cluster when empty.
+	TargetCluster string `json:"targetCluster"`
+
+	// Delete the clone after this duration, e.g. "72h". Stored in the cloner.io/expires-at namespace annotation.
+	TTL string `json:"ttl"`
+	// Delete the clone at this time (RFC 3339), instead of a TTL
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// persisted returns the options kept on a cloned namespace for resyncs. Settings that only apply to the
+// request or that depend on the server's configuration are left out, as are substituted secret values.
+func (o CloneOptions) persisted() CloneOptions {
+	o.DryRun = false
+	o.ServerDryRun = false
+	o.Wait = false
+	o.TTL = ""
+	o.ExpiresAt = nil
+	o.TargetCluster = ""
+	o.QuotaProfile = ""
+	o.Secrets = o.Secrets.withoutValues()
+	return o
+}
+
+// getCloneOptions reads the options a namespace was cloned with, empty options for clones made before they
+// were recorded
+func getCloneOptions(clientset *kubernetes.Clientset, targetNamespace string) (CloneOptions, *Error) {
+	var options CloneOptions
+	namespace, err := clientset.CoreV1().Namespaces().Get(context.TODO(), targetNamespace, metav1.GetOptions{})
+	if err != nil {
+		return options, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error getting namespace %s: %v", targetNamespace, err),
+		}
+	}
+	value, ok := namespace.Annotations[TARGET_CLONE_OPTIONS_ANNOTATION]
+	if !ok {
+		return options, nil
+	}
+	if err := json.Unmarshal([]byte(value), &options); err != nil {
+		return options, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Invalid %s annotation on namespace %s: %v", TARGET_CLONE_OPTIONS_ANNOTATION, targetNamespace, err),
+		}
+	}
+	// Compiles the patterns of the options
+	if errObj := options.Validate(); errObj != nil {
+		return options, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Invalid %s annotation on namespace %s: %s", TARGET_CLONE_OPTIONS_ANNOTATION, targetNamespace, errObj.Message),
+		}
+	}
+	return options, nil
+}
+
+// compilePattern compiles a rule pattern matched against a whole value
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// Validate checks the options before a job is started so bad input is rejected up front
+func (o CloneOptions) Validate() *Error {
+	if o.LabelSelector != "" {
+		if _, err := labels.Parse(o.LabelSelector); err != nil {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid labelSelector %q: %v", o.LabelSelector, err),
+			}
+		}
+	}
+	if o.NameRegex != "" {
+		if _, err := regexp.Compile(o.NameRegex); err != nil {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid nameRegex %q: %v", o.NameRegex, err),
+			}
+		}
+	}
+	if errObj := o.Images.validate(); errObj != nil {
+		return errObj
+	}
+	if errObj := o.Hosts.validate(); errObj != nil {
+		return errObj
+	}
+	if errObj := o.VirtualServices.validate(); errObj != nil {
+		return errObj
+	}
+	if errObj := o.NetworkIsolation.validate(); errObj != nil {
+		return errObj
+	}
+	if o.QuotaProfile != "" {
+		if _, errObj := getQuotaProfile(o.QuotaProfile); errObj != nil {
+			return errObj
+		}
+	}
+	if errObj := o.Secrets.validate(); errObj != nil {
+		return errObj
+	}
+	if errObj := o.StatefulSets.validate(); errObj != nil {
+		return errObj
+	}
+	if errObj := o.Volumes.validate(); errObj != nil {
+		return errObj
+	}
+	if o.Volumes.copiesData() && o.TargetCluster != "" {
+		return &Error{
+			Code:    http.StatusBadRequest,
+			Message: "volumes can only be cloned with their data within a cluster, use the empty strategy with targetCluster",
+		}
+	}
+	if errObj := o.HeaderRouting.validate(); errObj != nil {
+		return errObj
+	}
+	if o.HeaderRouting != nil && o.TargetCluster != "" {
+		return &Error{
+			Code:    http.StatusBadRequest,
+			Message: "headerRouting can't be used with targetCluster, the mesh of the source can't reach another cluster",
+		}
+	}
+	if errObj := o.Readiness.validate(); errObj != nil {
+		return errObj
+	}
+	for kind, value := range o.ReadinessTimeouts {
+		if timeout, err := time.ParseDuration(value); err != nil || timeout <= 0 {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid readiness timeout %q for %s", value, kind),
+			}
+		}
+	}
+	if _, errObj := parseExpiry(o.TTL, o.ExpiresAt, time.Now()); errObj != nil {
+		return errObj
+	}
+	return nil
+}
+
+// parseExpiry turns a TTL or an absolute expiry time into the time a clone expires, nil when neither is set.
+// A TTL is counted from base.
+func parseExpiry(ttl string, expiresAt *time.Time, base time.Time) (*time.Time, *Error) {
+	if ttl != "" && expiresAt != nil {
+		return nil, &Error{
+			Code:    http.StatusBadRequest,
+			Message: "Only one of ttl and expiresAt can be set",
+		}
+	}
+	if ttl != "" {
+		duration, err := time.ParseDuration(ttl)
+		if err != nil || duration <= 0 {
+			return nil, &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid ttl %q, expected a positive duration like \"72h\"", ttl),
+			}
+		}
+		expiry := base.Add(duration).UTC()
+		return &expiry, nil
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(time.Now()) {
+			return nil, &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("expiresAt %s is in the past", expiresAt.Format(time.RFC3339)),
+			}
+		}
+		expiry := expiresAt.UTC()
+		return &expiry, nil
+	}
+	return nil, nil
+}
+
+const (
+	PlanActionCreate = "Create"
+	PlanActionSkip   = "Skip"
+	// An object outside of the target namespace that would be changed
+	PlanActionPatch = "Patch"
+)
+
+// Outcomes of a server side dry run for a planned object
+const (
+	ServerDryRunPassed  = "Passed"
+	ServerDryRunFailed  = "Failed"
+	ServerDryRunSkipped = "Skipped"
+)
+
+// PlannedObject is what a dry run would do for a single object
+type PlannedObject struct {
+	Kind                string                 `json:"kind"`
+	Name                string                 `json:"name"`
+	Action              string                 `json:"action"`
+	Reason              string                 `json:"reason,omitempty"`
+	Annotations         map[string]string      `json:"annotations,omitempty"`
+	Mutations           map[string]interface{} `json:"mutations,omitempty"`
+	ServerDryRun        string                 `json:"serverDryRun,omitempty"`
+	ServerDryRunMessage string                 `json:"serverDryRunMessage,omitempty"`
+}
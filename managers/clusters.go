@@ -0,0 +1,85 @@
+package managers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ClusterClients are the clients for one cluster a namespace can be cloned into
+type ClusterClients struct {
+	Name          string
+	Clientset     *kubernetes.Clientset
+	DynamicClient *dynamic.DynamicClient
+}
+
+var clusterRegistry = struct {
+	sync.RWMutex
+	clusters map[string]*ClusterClients
+}{clusters: make(map[string]*ClusterClients)}
+
+// LoadClusterRegistry registers every context of a kubeconfig file as a clone target cluster,
+// keyed by the context name. Contexts that can't be loaded are logged and skipped.
+func LoadClusterRegistry(kubeconfigPath string) error {
+	kubeconfig, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		return fmt.Errorf("error loading kubeconfig %s: %v", kubeconfigPath, err)
+	}
+
+	clusterRegistry.Lock()
+	defer clusterRegistry.Unlock()
+	for contextName := range kubeconfig.Contexts {
+		config, err := clientcmd.NewNonInteractiveClientConfig(*kubeconfig, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+		if err != nil {
+			log.Printf("Skipping kubeconfig context %s: %v\n", contextName, err)
+			continue
+		}
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			log.Printf("Skipping kubeconfig context %s, error creating clientset: %v\n", contextName, err)
+			continue
+		}
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			log.Printf("Skipping kubeconfig context %s, error creating dynamic client: %v\n", contextName, err)
+			continue
+		}
+		clusterRegistry.clusters[contextName] = &ClusterClients{
+			Name:          contextName,
+			Clientset:     clientset,
+			DynamicClient: dynamicClient,
+		}
+		log.Printf("Registered cluster %s\n", contextName)
+	}
+	return nil
+}
+
+func GetCluster(name string) (*ClusterClients, *Error) {
+	clusterRegistry.RLock()
+	defer clusterRegistry.RUnlock()
+	cluster, ok := clusterRegistry.clusters[name]
+	if !ok {
+		return nil, &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Cluster %s is not registered", name),
+		}
+	}
+	return cluster, nil
+}
+
+func ListClusters() []string {
+	clusterRegistry.RLock()
+	defer clusterRegistry.RUnlock()
+	names := make([]string, 0, len(clusterRegistry.clusters))
+	for name := range clusterRegistry.clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
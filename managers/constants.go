@@ -18,6 +18,41 @@ const (
 	TARGET_INGRESS_ANNOTATION         = "cloner.io/source-ingress"
 	TARGET_SA_ANNOTATION              = "cloner.io/source-serviceaccount"
 	TARGET_VIRTUAL_SERVICE_ANNOTATION = "cloner.io/source-virtualservice"
+	TARGET_NETWORK_POLICY_ANNOTATION  = "cloner.io/source-networkpolicy"
+	TARGET_OBJECT_ANNOTATION          = "cloner.io/source-object"
+	// RBAC objects, ClusterRoleBindings are cloned as RoleBindings of the same name
+	TARGET_ROLE_ANNOTATION                 = "cloner.io/source-role"
+	TARGET_ROLE_BINDING_ANNOTATION         = "cloner.io/source-rolebinding"
+	TARGET_CLUSTER_ROLE_BINDING_ANNOTATION = "cloner.io/source-clusterrolebinding"
+	// Label on cluster scoped objects created for a clone, deleted along with the cloned namespace
+	TARGET_NAMESPACE_LABEL = "cloner.io/target-namespace"
+	// Quotas, objects created from a quota profile carry the profile name instead of a source object
+	TARGET_RESOURCE_QUOTA_ANNOTATION = "cloner.io/source-resourcequota"
+	TARGET_LIMIT_RANGE_ANNOTATION    = "cloner.io/source-limitrange"
+	TARGET_QUOTA_PROFILE_ANNOTATION  = "cloner.io/quota-profile"
+	// PersistentVolumeClaims and the strategy their data was cloned with
+	TARGET_PVC_ANNOTATION          = "cloner.io/source-pvc"
+	TARGET_PVC_STRATEGY_ANNOTATION = "cloner.io/pvc-strategy"
+	// StatefulSets were created from the source object until they got a source annotation
+	TARGET_STS_ANNOTATION = "cloner.io/source-statefulset"
+	// Secret policy action a Secret was cloned with: copy, regenerate or substitute
+	TARGET_SECRET_ACTION_ANNOTATION = "cloner.io/secret-action"
+	// Source of cloned PodDisruptionBudgets and HorizontalPodAutoscalers
+	TARGET_PDB_ANNOTATION = "cloner.io/source-poddisruptionbudget"
+	TARGET_HPA_ANNOTATION = "cloner.io/source-horizontalpodautoscaler"
+	// VirtualServicePolicy a VirtualService was cloned with, as JSON, when the clone request set one
+	TARGET_VIRTUAL_SERVICE_POLICY_ANNOTATION = "cloner.io/virtualservice-policy"
+	// JSON list of fields changed locally on a cloned object, kept as is by a resync
+	TARGET_OVERRIDES_ANNOTATION = "cloner.io/overrides"
+	TARGET_SYNCED_AT_ANNOTATION = "cloner.io/synced-at"
+	// CloneOptions of a cloned namespace as JSON, used by resyncs to clone new source objects the same way
+	TARGET_CLONE_OPTIONS_ANNOTATION = "cloner.io/clone-options"
+	// RFC 3339 time after which the reaper deletes a cloned namespace
+	TARGET_EXPIRES_AT_ANNOTATION = "cloner.io/expires-at"
+	// Expiry time the last expiry warning was sent for
+	TARGET_EXPIRY_WARNED_ANNOTATION = "cloner.io/expiry-warned"
+	// Source replica count of a workload cloned with zero replicas, restored by a wake
+	TARGET_ORIGINAL_REPLICAS_ANNOTATION = "cloner.io/original-replicas"
 	// Kube-green specifics (Reference: https://kube-green.dev/docs/apireference_v1alpha1/)
 	KUBE_GREEN_SLEEPAT_ANNOTATION = "sleep-info.kube-green.com/sleep-time"
 	KUBE_GREEN_WAKEAT_ANNOTATION  = "sleep-info.kube-green.com/wake-up-time"
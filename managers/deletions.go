@@ -0,0 +1,171 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+type DeletionStatus struct {
+	ID        string   `json:"id"`
+	Namespace string   `json:"namespace"`
+	Cluster   string   `json:"cluster,omitempty"`
+	Phase     JobPhase `json:"phase"`
+	// Phase of the namespace itself while it terminates, e.g. "Terminating"
+	NamespacePhase string `json:"namespacePhase,omitempty"`
+	// What the namespace is still waiting for, from its status conditions
+	Message   string     `json:"message,omitempty"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+	EndTime   *time.Time `json:"endTime,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+type deletionJob struct {
+	mu     sync.RWMutex
+	status DeletionStatus
+}
+
+var deletionJobs = struct {
+	sync.RWMutex
+	jobs map[string]*deletionJob
+}{jobs: make(map[string]*deletionJob)}
+
+// pruneDeletionJobs forgets deletions that finished more than JobRetention ago. Must be called with deletionJobs locked.
+func pruneDeletionJobs(now time.Time) {
+	for id, deletion := range deletionJobs.jobs {
+		endTime := deletion.Status().EndTime
+		if endTime != nil && now.Sub(*endTime) > JobRetention {
+			delete(deletionJobs.jobs, id)
+		}
+	}
+}
+
+func (d *deletionJob) Status() DeletionStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.status
+}
+
+func (d *deletionJob) update(update func(status *DeletionStatus)) {
+	d.mu.Lock()
+	update(&d.status)
+	d.mu.Unlock()
+}
+
+// DeleteClonedNamespace starts deleting a namespace created by CloneNamespace in the background.
+// confirm is optional, when set it must be the namespace name. cluster names the registered cluster
+// of clientset and dynamicClient, empty for the source cluster.
+func DeleteClonedNamespace(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, cluster, namespace, confirm string) (DeletionStatus, *Error) {
+	if confirm != "" && confirm != namespace {
+		return DeletionStatus{}, &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Confirmation %q does not match namespace %s", confirm, namespace),
+		}
+	}
+	ns, err := clientset.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return DeletionStatus{}, &Error{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Namespace %s not found", namespace),
+			}
+		}
+		return DeletionStatus{}, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	if errObj := validateNamespaceDeletion(ns); errObj != nil {
+		return DeletionStatus{}, errObj
+	}
+	return startDeletion(clientset, dynamicClient, cluster, namespace)
+}
+
+// startDeletion registers a deletion job for namespace and removes it in the background. It fails with a
+// conflict when the namespace is already being deleted.
+func startDeletion(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, cluster, namespace string) (DeletionStatus, *Error) {
+	deletionJobs.Lock()
+	pruneDeletionJobs(time.Now())
+	for _, existing := range deletionJobs.jobs {
+		status := existing.Status()
+		if status.Namespace == namespace && status.Cluster == cluster && (status.Phase == JobPhasePending || status.Phase == JobPhaseRunning) {
+			deletionJobs.Unlock()
+			return status, &Error{
+				Code:    http.StatusConflict,
+				Message: fmt.Sprintf("Namespace %s is already being deleted by %s", namespace, status.ID),
+			}
+		}
+	}
+	now := time.Now()
+	deletion := &deletionJob{status: DeletionStatus{
+		ID:        rand.String(10),
+		Namespace: namespace,
+		Cluster:   cluster,
+		Phase:     JobPhaseRunning,
+		StartTime: &now,
+	}}
+	id := deletion.status.ID
+	deletionJobs.jobs[id] = deletion
+	deletionJobs.Unlock()
+
+	go func() {
+		errObj := removeNamespace(clientset, dynamicClient, namespace, func(ns *v1.Namespace) {
+			deletion.update(func(status *DeletionStatus) {
+				status.NamespacePhase = string(ns.Status.Phase)
+				status.Message = terminatingMessage(ns)
+			})
+		})
+		end := time.Now()
+		deletion.update(func(status *DeletionStatus) {
+			status.EndTime = &end
+			status.NamespacePhase = ""
+			status.Message = ""
+			if errObj != nil {
+				status.Phase = JobPhaseFailed
+				status.Error = errObj.Message
+				return
+			}
+			status.Phase = JobPhaseSucceeded
+		})
+		if errObj != nil {
+			log.Printf("Deletion %s of namespace %s failed: %s\n", id, namespace, errObj.Message)
+		}
+	}()
+	return deletion.Status(), nil
+}
+
+// terminatingMessage summarizes the conditions a terminating namespace is waiting on
+func terminatingMessage(ns *v1.Namespace) string {
+	var messages []string
+	for _, condition := range ns.Status.Conditions {
+		if condition.Status == v1.ConditionTrue && condition.Message != "" {
+			messages = append(messages, condition.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+func GetDeletion(id string) (DeletionStatus, *Error) {
+	deletionJobs.Lock()
+	pruneDeletionJobs(time.Now())
+	deletion, ok := deletionJobs.jobs[id]
+	deletionJobs.Unlock()
+	if !ok {
+		return DeletionStatus{}, &Error{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("Deletion %s not found", id),
+		}
+	}
+	return deletion.Status(), nil
+}
@@ -0,0 +1,483 @@
+package managers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// FieldDiff is a field that differs between a source object and its clone. Source or Target is
+// left out when the field only exists on one side.
+type FieldDiff struct {
+	Field  string      `json:"field"`
+	Source interface{} `json:"source,omitempty"`
+	Target interface{} `json:"target,omitempty"`
+}
+
+type ObjectDrift struct {
+	Kind        string      `json:"kind"`
+	Name        string      `json:"name"`
+	SourceName  string      `json:"sourceName"`
+	Differences []FieldDiff `json:"differences"`
+}
+
+type ObjectRef struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+type DriftReport struct {
+	SourceNamespace string `json:"sourceNamespace"`
+	TargetNamespace string `json:"targetNamespace"`
+	// Cloned objects that differ from their source object
+	Drifted []ObjectDrift `json:"drifted"`
+	// Source objects without a clone
+	OnlyInSource []ObjectRef `json:"onlyInSource"`
+	// Objects in the clone without a source object, created locally or deleted from the source
+	OnlyInTarget []ObjectRef `json:"onlyInTarget"`
+}
+
+// pair matches target objects to their source objects through the source annotation set by the
+// Clone* functions, or by name when the kind has no source annotation. Unmatched objects are added
+// to the report and the matched pairs are returned as [source, target].
+func (r *DriftReport) pair(kind, annotation string, sources, targets []metav1.Object) [][2]metav1.Object {
+	sourcesByName := make(map[string]metav1.Object)
+	for _, source := range sources {
+		sourcesByName[source.GetName()] = source
+	}
+	paired := make(map[string]bool)
+	var pairs [][2]metav1.Object
+	for _, target := range targets {
+		sourceName := target.GetName()
+		if annotation != "" {
+			name, ok := target.GetAnnotations()[annotation]
+			if !ok {
+				r.OnlyInTarget = append(r.OnlyInTarget, ObjectRef{Kind: kind, Name: target.GetName()})
+				continue
+			}
+			sourceName = name
+		}
+		source, ok := sourcesByName[sourceName]
+		if !ok {
+			r.OnlyInTarget = append(r.OnlyInTarget, ObjectRef{Kind: kind, Name: target.GetName()})
+			continue
+		}
+		paired[sourceName] = true
+		pairs = append(pairs, [2]metav1.Object{source, target})
+	}
+	for _, source := range sources {
+		if !paired[source.GetName()] {
+			r.OnlyInSource = append(r.OnlyInSource, ObjectRef{Kind: kind, Name: source.GetName()})
+		}
+	}
+	return pairs
+}
+
+func (r *DriftReport) add(kind string, source, target metav1.Object, differences []FieldDiff) {
+	if len(differences) == 0 {
+		return
+	}
+	sort.Slice(differences, func(i, j int) bool { return differences[i].Field < differences[j].Field })
+	r.Drifted = append(r.Drifted, ObjectDrift{
+		Kind:        kind,
+		Name:        target.GetName(),
+		SourceName:  source.GetName(),
+		Differences: differences,
+	})
+}
+
+// hashValue identifies a secret value without exposing it
+func hashValue(value []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(value))
+}
+
+// diffMaps compares two maps key by key, show turns a value into what is reported
+func diffMaps[V any](prefix string, source, target map[string]V, equal func(a, b V) bool, show func(V) interface{}) []FieldDiff {
+	var differences []FieldDiff
+	for key, value := range source {
+		targetValue, ok := target[key]
+		if !ok {
+			differences = append(differences, FieldDiff{Field: prefix + key, Source: show(value)})
+		} else if !equal(value, targetValue) {
+			differences = append(differences, FieldDiff{Field: prefix + key, Source: show(value), Target: show(targetValue)})
+		}
+	}
+	for key, value := range target {
+		if _, ok := source[key]; !ok {
+			differences = append(differences, FieldDiff{Field: prefix + key, Target: show(value)})
+		}
+	}
+	return differences
+}
+
+// envValue is how an environment variable is reported, the literal value or its source reference
+func envValue(env v1.EnvVar) interface{} {
+	if env.ValueFrom != nil {
+		return env.ValueFrom
+	}
+	return env.Value
+}
+
+func diffContainers(path string, source, target []v1.Container) []FieldDiff {
+	var differences []FieldDiff
+	targets := make(map[string]v1.Container)
+	for _, container := range target {
+		targets[container.Name] = container
+	}
+	for _, container := range source {
+		field := fmt.Sprintf("%s[%s]", path, container.Name)
+		targetContainer, ok := targets[container.Name]
+		if !ok {
+			differences = append(differences, FieldDiff{Field: field, Source: container.Image})
+			continue
+		}
+		delete(targets, container.Name)
+		if container.Image != targetContainer.Image {
+			differences = append(differences, FieldDiff{Field: field + ".image", Source: container.Image, Target: targetContainer.Image})
+		}
+		sourceEnv := make(map[string]v1.EnvVar)
+		for _, env := range container.Env {
+			sourceEnv[env.Name] = env
+		}
+		targetEnv := make(map[string]v1.EnvVar)
+		for _, env := range targetContainer.Env {
+			targetEnv[env.Name] = env
+		}
+		differences = append(differences, diffMaps(field+".env.", sourceEnv, targetEnv,
+			func(a, b v1.EnvVar) bool { return equality.Semantic.DeepEqual(a, b) }, envValue)...)
+	}
+	for name, container := range targets {
+		differences = append(differences, FieldDiff{Field: fmt.Sprintf("%s[%s]", path, name), Target: container.Image})
+	}
+	return differences
+}
+
+func diffPodSpec(path string, source, target *v1.PodSpec) []FieldDiff {
+	differences := diffContainers(path+".initContainers", source.InitContainers, target.InitContainers)
+	return append(differences, diffContainers(path+".containers", source.Containers, target.Containers)...)
+}
+
+func diffReplicas(source, target *int32) []FieldDiff {
+	if equality.Semantic.DeepEqual(source, target) {
+		return nil
+	}
+	diff := FieldDiff{Field: "spec.replicas"}
+	if source != nil {
+		diff.Source = *source
+	}
+	if target != nil {
+		diff.Target = *target
+	}
+	return []FieldDiff{diff}
+}
+
+// servicePortKey identifies a service port by name, or by port number for unnamed ports
+func servicePortKey(port v1.ServicePort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	return fmt.Sprintf("%d", port.Port)
+}
+
+func servicePortValue(port v1.ServicePort) interface{} {
+	return fmt.Sprintf("%d/%s -> %s", port.Port, port.Protocol, port.TargetPort.String())
+}
+
+// expectedVirtualService returns a source VirtualService with its destinations and gateways rewritten by the
+// VirtualServicePolicy recorded on its clone, the way CloneIstioVirtualServices rewrote them
+func expectedVirtualService(source, target *unstructured.Unstructured, sourceNamespace, targetNamespace string) (*unstructured.Unstructured, error) {
+	var policy *VirtualServicePolicy
+	if value, ok := target.GetAnnotations()[TARGET_VIRTUAL_SERVICE_POLICY_ANNOTATION]; ok {
+		policy = &VirtualServicePolicy{}
+		if err := json.Unmarshal([]byte(value), policy); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation on VirtualService %s: %v", TARGET_VIRTUAL_SERVICE_POLICY_ANNOTATION, target.GetName(), err)
+		}
+	}
+	expected := source.DeepCopy()
+	rewrite := istioRewrite{source: sourceNamespace, target: targetNamespace}
+	if err := policy.rewrite(expected, rewrite, newRewriteLog("VirtualService", source.GetName())); err != nil {
+		return nil, fmt.Errorf("error rewriting VirtualService %s: %v", source.GetName(), err)
+	}
+	return expected, nil
+}
+
+// diffRoutes compares VirtualService routes, by name when routes are named and by position otherwise
+func diffRoutes(source, target *unstructured.Unstructured, targetNamespace string) []FieldDiff {
+	var differences []FieldDiff
+	for _, protocol := range []string{"http", "tcp", "tls"} {
+		sourceRoutes, _, _ := unstructured.NestedSlice(source.Object, "spec", protocol)
+		targetRoutes, _, _ := unstructured.NestedSlice(target.Object, "spec", protocol)
+		routeKey := func(i int, route interface{}) string {
+			if m, ok := route.(map[string]interface{}); ok {
+				if name, ok := m["name"].(string); ok && name != "" {
+					return name
+				}
+			}
+			return fmt.Sprintf("%d", i)
+		}
+		// Routes to clones added by header routing aren't part of the source
+		sourceRoutes = withoutCloneRoutes(sourceRoutes, "")
+		if protocol == "http" {
+			// Neither are the mirrors to this clone
+			sourceRoutes = withoutCloneMirrors(sourceRoutes, "", targetNamespace, nil)
+		}
+		sourceByKey := make(map[string]interface{})
+		for i, route := range sourceRoutes {
+			sourceByKey[routeKey(i, route)] = route
+		}
+		targetByKey := make(map[string]interface{})
+		for i, route := range targetRoutes {
+			targetByKey[routeKey(i, route)] = route
+		}
+		differences = append(differences, diffMaps(fmt.Sprintf("spec.%s.", protocol), sourceByKey, targetByKey,
+			func(a, b interface{}) bool { return equality.Semantic.DeepEqual(a, b) },
+			func(route interface{}) interface{} { return route })...)
+	}
+	return differences
+}
+
+// GetNamespaceDrift reports how far a namespace created by CloneNamespace has drifted from its source
+func GetNamespaceDrift(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, targetNamespace string) (DriftReport, *Error) {
+	namespace, err := clientset.CoreV1().Namespaces().Get(context.TODO(), targetNamespace, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return DriftReport{}, &Error{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Namespace %s not found", targetNamespace),
+			}
+		}
+		return DriftReport{}, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	sourceNamespace := namespace.Annotations[TARGET_NS_ANNOTATION]
+	if namespace.Annotations[TARGET_NS_ANNOTATION_ENABLED] != "true" || sourceNamespace == "" {
+		return DriftReport{}, &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Namespace %s is not a clone", targetNamespace),
+		}
+	}
+
+	report := DriftReport{
+		SourceNamespace: sourceNamespace,
+		TargetNamespace: targetNamespace,
+		Drifted:         []ObjectDrift{},
+		OnlyInSource:    []ObjectRef{},
+		OnlyInTarget:    []ObjectRef{},
+	}
+	drifts := []func(*kubernetes.Clientset, dynamic.Interface, *DriftReport) error{
+		driftConfigMaps,
+		driftSecrets,
+		driftDeployments,
+		driftStatefulSets,
+		driftServices,
+		driftVirtualServices,
+	}
+	for _, drift := range drifts {
+		if err := drift(clientset, dynamicClient, &report); err != nil {
+			return report, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			}
+		}
+	}
+	return report, nil
+}
+
+func driftConfigMaps(clientset *kubernetes.Clientset, _ dynamic.Interface, report *DriftReport) error {
+	sources, err := clientset.CoreV1().ConfigMaps(report.SourceNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing ConfigMaps in %s: %v", report.SourceNamespace, err)
+	}
+	targets, err := clientset.CoreV1().ConfigMaps(report.TargetNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing ConfigMaps in %s: %v", report.TargetNamespace, err)
+	}
+	var sourceObjects, targetObjects []metav1.Object
+	for i := range sources.Items {
+		// Every namespace gets its own root CA ConfigMap, it is never cloned
+		if sources.Items[i].Name == "kube-root-ca.crt" {
+			continue
+		}
+		sourceObjects = append(sourceObjects, &sources.Items[i])
+	}
+	for i := range targets.Items {
+		if targets.Items[i].Name == "kube-root-ca.crt" {
+			continue
+		}
+		targetObjects = append(targetObjects, &targets.Items[i])
+	}
+	for _, pair := range report.pair("ConfigMap", TARGET_CM_ANNOTATION, sourceObjects, targetObjects) {
+		source, target := pair[0].(*v1.ConfigMap), pair[1].(*v1.ConfigMap)
+		report.add("ConfigMap", source, target, diffMaps("data.", source.Data, target.Data,
+			func(a, b string) bool { return a == b },
+			func(value string) interface{} { return value }))
+	}
+	return nil
+}
+
+func driftSecrets(clientset *kubernetes.Clientset, _ dynamic.Interface, report *DriftReport) error {
+	sources, err := clientset.CoreV1().Secrets(report.SourceNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing Secrets in %s: %v", report.SourceNamespace, err)
+	}
+	targets, err := clientset.CoreV1().Secrets(report.TargetNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing Secrets in %s: %v", report.TargetNamespace, err)
+	}
+	var sourceObjects, targetObjects []metav1.Object
+	for i := range sources.Items {
+		// Secrets the cloner never copies are not drift
+		if secretSkipReason(&sources.Items[i]) != "" {
+			continue
+		}
+		sourceObjects = append(sourceObjects, &sources.Items[i])
+	}
+	for i := range targets.Items {
+		if secretSkipReason(&targets.Items[i]) != "" {
+			continue
+		}
+		targetObjects = append(targetObjects, &targets.Items[i])
+	}
+	for _, pair := range report.pair("Secret", TARGET_SECRET_ANNOTATION, sourceObjects, targetObjects) {
+		source, target := pair[0].(*v1.Secret), pair[1].(*v1.Secret)
+		// Values are compared and reported by hash only
+		report.add("Secret", source, target, diffMaps("data.", source.Data, target.Data,
+			func(a, b []byte) bool { return hashValue(a) == hashValue(b) },
+			func(value []byte) interface{} { return hashValue(value) }))
+	}
+	return nil
+}
+
+func driftDeployments(clientset *kubernetes.Clientset, _ dynamic.Interface, report *DriftReport) error {
+	sources, err := clientset.AppsV1().Deployments(report.SourceNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing Deployments in %s: %v", report.SourceNamespace, err)
+	}
+	targets, err := clientset.AppsV1().Deployments(report.TargetNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing Deployments in %s: %v", report.TargetNamespace, err)
+	}
+	var sourceObjects, targetObjects []metav1.Object
+	for i := range sources.Items {
+		sourceObjects = append(sourceObjects, &sources.Items[i])
+	}
+	for i := range targets.Items {
+		targetObjects = append(targetObjects, &targets.Items[i])
+	}
+	for _, pair := range report.pair("Deployment", TARGET_DEPLOYMENT_ANNOTATION, sourceObjects, targetObjects) {
+		source, target := pair[0].(*appsv1.Deployment), pair[1].(*appsv1.Deployment)
+		differences := diffReplicas(source.Spec.Replicas, target.Spec.Replicas)
+		differences = append(differences, diffPodSpec("spec.template.spec", &source.Spec.Template.Spec, &target.Spec.Template.Spec)...)
+		report.add("Deployment", source, target, differences)
+	}
+	return nil
+}
+
+func driftStatefulSets(clientset *kubernetes.Clientset, _ dynamic.Interface, report *DriftReport) error {
+	sources, err := clientset.AppsV1().StatefulSets(report.SourceNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing StatefulSets in %s: %v", report.SourceNamespace, err)
+	}
+	targets, err := clientset.AppsV1().StatefulSets(report.TargetNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing StatefulSets in %s: %v", report.TargetNamespace, err)
+	}
+	var sourceObjects, targetObjects []metav1.Object
+	for i := range sources.Items {
+		sourceObjects = append(sourceObjects, &sources.Items[i])
+	}
+	for i := range targets.Items {
+		targetObjects = append(targetObjects, &targets.Items[i])
+	}
+	for _, pair := range report.pair("StatefulSet", TARGET_STS_ANNOTATION, sourceObjects, targetObjects) {
+		source, target := pair[0].(*appsv1.StatefulSet), pair[1].(*appsv1.StatefulSet)
+		differences := diffReplicas(source.Spec.Replicas, target.Spec.Replicas)
+		differences = append(differences, diffPodSpec("spec.template.spec", &source.Spec.Template.Spec, &target.Spec.Template.Spec)...)
+		report.add("StatefulSet", source, target, differences)
+	}
+	return nil
+}
+
+func driftServices(clientset *kubernetes.Clientset, _ dynamic.Interface, report *DriftReport) error {
+	sources, err := clientset.CoreV1().Services(report.SourceNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing Services in %s: %v", report.SourceNamespace, err)
+	}
+	targets, err := clientset.CoreV1().Services(report.TargetNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing Services in %s: %v", report.TargetNamespace, err)
+	}
+	var sourceObjects, targetObjects []metav1.Object
+	for i := range sources.Items {
+		// Service types the cloner never copies are not drift
+		if !slices.Contains(ClonedServiceTypes, sources.Items[i].Spec.Type) {
+			continue
+		}
+		sourceObjects = append(sourceObjects, &sources.Items[i])
+	}
+	for i := range targets.Items {
+		targetObjects = append(targetObjects, &targets.Items[i])
+	}
+	for _, pair := range report.pair("Service", TARGET_SERVICE_ANNOTATION, sourceObjects, targetObjects) {
+		source, target := pair[0].(*v1.Service), pair[1].(*v1.Service)
+		sourcePorts := make(map[string]v1.ServicePort)
+		for _, port := range source.Spec.Ports {
+			sourcePorts[servicePortKey(port)] = port
+		}
+		targetPorts := make(map[string]v1.ServicePort)
+		for _, port := range target.Spec.Ports {
+			targetPorts[servicePortKey(port)] = port
+		}
+		// Node ports are allocated per service, only the ports themselves are compared
+		report.add("Service", source, target, diffMaps("spec.ports.", sourcePorts, targetPorts,
+			func(a, b v1.ServicePort) bool { return servicePortValue(a) == servicePortValue(b) },
+			servicePortValue))
+	}
+	return nil
+}
+
+func driftVirtualServices(_ *kubernetes.Clientset, dynamicClient dynamic.Interface, report *DriftReport) error {
+	sources, err := dynamicClient.Resource(virtualServiceGVR).Namespace(report.SourceNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Istio is not installed
+			return nil
+		}
+		return fmt.Errorf("error listing VirtualServices in %s: %v", report.SourceNamespace, err)
+	}
+	targets, err := dynamicClient.Resource(virtualServiceGVR).Namespace(report.TargetNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing VirtualServices in %s: %v", report.TargetNamespace, err)
+	}
+	var sourceObjects, targetObjects []metav1.Object
+	for i := range sources.Items {
+		sourceObjects = append(sourceObjects, &sources.Items[i])
+	}
+	for i := range targets.Items {
+		targetObjects = append(targetObjects, &targets.Items[i])
+	}
+	for _, pair := range report.pair("VirtualService", TARGET_VIRTUAL_SERVICE_ANNOTATION, sourceObjects, targetObjects) {
+		source, target := pair[0].(*unstructured.Unstructured), pair[1].(*unstructured.Unstructured)
+		expected, err := expectedVirtualService(source, target, report.SourceNamespace, report.TargetNamespace)
+		if err != nil {
+			return err
+		}
+		report.add("VirtualService", source, target, diffRoutes(expected, target, report.TargetNamespace))
+	}
+	return nil
+}
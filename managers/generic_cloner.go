@@ -0,0 +1,261 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Resources never cloned by the generic cloner, either as "resource" or "resource.group".
+// These are runtime state or objects recreated by their controllers. Extended with -generic-deny-list.
+var GenericClonerDenyList = []string{
+	"events",
+	"events.events.k8s.io",
+	"pods",
+	"endpoints",
+	"endpointslices.discovery.k8s.io",
+	"leases.coordination.k8s.io",
+	"replicasets.apps",
+	"controllerrevisions.apps",
+	"podtemplates",
+	"localsubjectaccessreviews.authorization.k8s.io",
+	"volumesnapshots.snapshot.storage.k8s.io",
+}
+
+// Resources already cloned by the typed Clone* functions, skipped by the generic cloner to avoid duplicates
+var typedClonerResources = []string{
+	"configmaps",
+	"secrets",
+	"serviceaccounts",
+	"resourcequotas",
+	"limitranges",
+	"persistentvolumeclaims",
+	"services",
+	"deployments.apps",
+	"statefulsets.apps",
+	"jobs.batch",
+	"cronjobs.batch",
+	"ingresses.extensions",
+	"ingresses.networking.k8s.io",
+	"networkpolicies.networking.k8s.io",
+	"roles.rbac.authorization.k8s.io",
+	"rolebindings.rbac.authorization.k8s.io",
+	"poddisruptionbudgets.policy",
+	"horizontalpodautoscalers.autoscaling",
+	"virtualservices.networking.istio.io",
+	"gateways.networking.istio.io",
+	"destinationrules.networking.istio.io",
+	"serviceentries.networking.istio.io",
+	"sidecars.networking.istio.io",
+	"peerauthentications.security.istio.io",
+	"authorizationpolicies.security.istio.io",
+	"requestauthentications.security.istio.io",
+	"sleepinfos.kube-green.com",
+}
+
+// Metadata populated by the API server that must not be sent on create
+var serverPopulatedMetadata = []string{
+	"uid",
+	"resourceVersion",
+	"generation",
+	"creationTimestamp",
+	"deletionTimestamp",
+	"deletionGracePeriodSeconds",
+	"managedFields",
+	"selfLink",
+	"ownerReferences",
+}
+
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// resourceMatches checks a resource against a list of "resource" or "resource.group" names
+func resourceMatches(list []string, gvr schema.GroupVersionResource) bool {
+	qualified := gvr.Resource
+	if gvr.Group != "" {
+		qualified = gvr.Resource + "." + gvr.Group
+	}
+	for _, name := range list {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == gvr.Resource || name == qualified {
+			return true
+		}
+	}
+	return false
+}
+
+// getClonableResources uses discovery to find every namespaced resource type that can be listed and created
+func getClonableResources(clientset *kubernetes.Clientset) (map[schema.GroupVersionResource]metav1.APIResource, *Error) {
+	resourceLists, err := clientset.Discovery().ServerPreferredNamespacedResources()
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error discovering API resources: %v", err),
+			}
+		}
+		// Partial results are still usable, an unavailable aggregated API shouldn't fail the clone
+		log.Printf("Some API groups could not be discovered: %v\n", err)
+	}
+	resourceLists = discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list", "create"}}, resourceLists)
+
+	resources := make(map[schema.GroupVersionResource]metav1.APIResource)
+	for _, resourceList := range resourceLists {
+		groupVersion, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			log.Printf("Skipping API group version %s: %v\n", resourceList.GroupVersion, err)
+			continue
+		}
+		for _, resource := range resourceList.APIResources {
+			// Subresources like pods/log are not objects of their own
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			resources[groupVersion.WithResource(resource.Name)] = resource
+		}
+	}
+	return resources, nil
+}
+
+// Annotations recording how a PersistentVolumeClaim was bound, they don't apply to a new claim
+var pvcBindingAnnotations = []string{
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"volume.kubernetes.io/selected-node",
+}
+
+// sanitizeForClone strips server populated fields and status from an object and moves it to the target namespace
+func sanitizeForClone(item *unstructured.Unstructured, targetNamespace string) {
+	for _, field := range serverPopulatedMetadata {
+		unstructured.RemoveNestedField(item.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(item.Object, "status")
+	item.SetNamespace(targetNamespace)
+
+	// A cloned claim must get a volume of its own instead of the source's PersistentVolume
+	if item.GetKind() == "PersistentVolumeClaim" {
+		unstructured.RemoveNestedField(item.Object, "spec", "volumeName")
+		annotations := item.GetAnnotations()
+		for _, annotation := range pvcBindingAnnotations {
+			delete(annotations, annotation)
+		}
+		item.SetAnnotations(annotations)
+	}
+}
+
+// CloneGenericResources clones every namespaced resource type found through discovery that isn't handled
+// by a typed Clone* function or denied by GenericClonerDenyList and the job's DenyResources.
+func CloneGenericResources(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetDynamicClient := job.targetDynamicClient(dynamicClient)
+	resources, errObj := getClonableResources(clientset)
+	if errObj != nil {
+		return errObj
+	}
+	var denyResources []string
+	if job != nil {
+		denyResources = job.Options.DenyResources
+	}
+
+	// Clone resource types in a stable order so plans and job reports are comparable between runs
+	gvrs := make([]schema.GroupVersionResource, 0, len(resources))
+	for gvr := range resources {
+		gvrs = append(gvrs, gvr)
+	}
+	sort.Slice(gvrs, func(i, j int) bool { return gvrs[i].String() < gvrs[j].String() })
+
+	for _, gvr := range gvrs {
+		resource := resources[gvr]
+		if resourceMatches(typedClonerResources, gvr) || resourceMatches(GenericClonerDenyList, gvr) || resourceMatches(denyResources, gvr) {
+			continue
+		}
+		if !job.includesKind(resource.Kind) {
+			continue
+		}
+		items, err := dynamicClient.Resource(gvr).Namespace(sourceNamespace).List(context.TODO(), job.listOptions())
+		if err != nil {
+			if errors.IsNotFound(err) || errors.IsForbidden(err) || errors.IsMethodNotSupported(err) {
+				log.Printf("Skipping %s: %v\n", gvr.String(), err)
+				continue
+			}
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error listing %s: %v", gvr.String(), err),
+			}
+		}
+
+		for _, item := range items.Items {
+			if !job.matchesName(item.GetName()) {
+				continue
+			}
+			if len(item.GetOwnerReferences()) > 0 {
+				// Owned objects are recreated by their controllers once the owner is cloned
+				job.skip(resource.Kind, item.GetName(), "object is owned by another object")
+				continue
+			}
+			_, err := targetDynamicClient.Resource(gvr).Namespace(targetNamespace).Get(context.TODO(), item.GetName(), metav1.GetOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return &Error{
+					Code:    http.StatusInternalServerError,
+					Message: fmt.Sprintf("Error checking for existing %s %s: %v", resource.Kind, item.GetName(), err),
+				}
+			} else if err == nil {
+				log.Printf("%s %s already exists in %s, skipping creation\n", resource.Kind, item.GetName(), targetNamespace)
+				job.skip(resource.Kind, item.GetName(), "already exists")
+				continue
+			}
+
+			sanitizeForClone(&item, targetNamespace)
+			annotations := item.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			delete(annotations, lastAppliedConfigAnnotation)
+			annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+			annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+			annotations[TARGET_OBJECT_ANNOTATION] = gvr.GroupResource().String() + "/" + item.GetName()
+			item.SetAnnotations(annotations)
+
+			newObject := item.DeepCopy()
+			created, errObj := job.createResource(gvr, resource.Kind, item.GetName(), annotations, nil, func(opts metav1.CreateOptions) error {
+				_, err := targetDynamicClient.Resource(gvr).Namespace(targetNamespace).Create(context.TODO(), newObject, opts)
+				return err
+			})
+			if errObj != nil {
+				return errObj
+			}
+			if !created {
+				continue
+			}
+			log.Printf("%s %s cloned to namespace %s\n", resource.Kind, item.GetName(), targetNamespace)
+			job.record(resource.Kind, item.GetName(), ObjectStatusReady, "")
+		}
+	}
+	return nil
+}
+
+// SetGenericClonerDenyList adds resources to the default deny list, or replaces it when replace is set. Used
+// for the -generic-deny-list and -replace-generic-deny-list flags.
+func SetGenericClonerDenyList(resources []string, replace bool) {
+	denyList := make([]string, 0, len(GenericClonerDenyList)+len(resources))
+	if !replace {
+		denyList = append(denyList, GenericClonerDenyList...)
+	}
+	for _, resource := range resources {
+		resource = strings.ToLower(strings.TrimSpace(resource))
+		if resource != "" && !slices.Contains(denyList, resource) {
+			denyList = append(denyList, resource)
+		}
+	}
+	GenericClonerDenyList = denyList
+}
@@ -0,0 +1,141 @@
+package managers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// defaultHostTemplate keeps the historical VirtualService behaviour of prefixing hosts with the target namespace
+const defaultHostTemplate = "{{.Target}}-{{.Host}}"
+
+// HostRewrite rewrites the hostnames of cloned Ingresses and VirtualServices so a clone never claims
+// the hosts of its source. Rules are tried in order, the first matching rule wins. Hosts matching no
+// rule are rendered with Template.
+type HostRewrite struct {
+	// Go template for the new host, e.g. "{{.Target}}.{{.Domain}}". Fields are Host, Subdomain
+	// (the first label of the host), Domain, Source and Target. Defaults to "{{.Target}}-{{.Host}}".
+	Template string `json:"template"`
+	// Domain used by Template, e.g. "dev.example.com". Defaults to the host without its first label.
+	Domain string `json:"domain"`
+	// Regular expression rules matched against the whole host
+	Rules []HostRewriteRule `json:"rules"`
+	// Copy the TLS secrets referenced by cloned Ingresses when the secret step did not clone them
+	CopyTLSSecrets bool `json:"copyTLSSecrets"`
+}
+
+type HostRewriteRule struct {
+	// Regular expression matched against the whole host, e.g. "(.*)\\.example\\.com"
+	Pattern string `json:"pattern"`
+	// Replacement host, may reference groups from Pattern, e.g. "$1.dev.example.com"
+	Replacement string `json:"replacement"`
+
+	// Pattern compiled by validate
+	pattern *regexp.Regexp
+}
+
+// hostTemplateData is what a host Template is rendered with
+type hostTemplateData struct {
+	Host      string
+	Subdomain string
+	Domain    string
+	Source    string
+	Target    string
+}
+
+func (o *HostRewrite) validate() *Error {
+	if o == nil {
+		return nil
+	}
+	if o.Template != "" {
+		if _, err := template.New("host").Option("missingkey=error").Parse(o.Template); err != nil {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid host template %q: %v", o.Template, err),
+			}
+		}
+	}
+	for i := range o.Rules {
+		rule := &o.Rules[i]
+		pattern, err := compilePattern(rule.Pattern)
+		if err != nil {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid host pattern %q: %v", rule.Pattern, err),
+			}
+		}
+		rule.pattern = pattern
+		if rule.Replacement == "" {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Host pattern %q needs a replacement", rule.Pattern),
+			}
+		}
+	}
+	return nil
+}
+
+// rewriteHost returns the host a clone uses in place of host. Service hosts qualified with the source
+// namespace, e.g. "svc.source.svc.cluster.local", move to the target namespace. "*" is kept as is and
+// the wildcard label of "*.example.com" is kept in front of the rewritten rest of the host.
+func (o *HostRewrite) rewriteHost(host, source, target string) (string, error) {
+	if host == "" || host == "*" {
+		return host, nil
+	}
+	if strings.HasPrefix(host, "*.") {
+		rewritten, err := o.rewriteHost(strings.TrimPrefix(host, "*."), source, target)
+		return "*." + rewritten, err
+	}
+	hostTemplate := defaultHostTemplate
+	domain := ""
+	if o != nil {
+		for _, rule := range o.Rules {
+			if rule.pattern.MatchString(host) {
+				return rule.pattern.ReplaceAllString(host, rule.Replacement), nil
+			}
+		}
+	}
+	// Service hosts qualified with the source namespace point to the cloned service instead
+	if rewritten, ok := rewriteServiceHost(host, source, target); ok {
+		return rewritten, nil
+	}
+	if o != nil {
+		if o.Template != "" {
+			hostTemplate = o.Template
+		}
+		domain = o.Domain
+	}
+	subdomain, rest, _ := strings.Cut(host, ".")
+	if domain == "" {
+		domain = rest
+	}
+	tmpl, err := template.New("host").Option("missingkey=error").Parse(hostTemplate)
+	if err != nil {
+		return "", err
+	}
+	var rendered bytes.Buffer
+	data := hostTemplateData{Host: host, Subdomain: subdomain, Domain: domain, Source: source, Target: target}
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf("error rendering host template for %s: %v", host, err)
+	}
+	return strings.Trim(rendered.String(), "."), nil
+}
+
+// rewriteHosts rewrites every host of a list, recording the changes under path
+func (o *HostRewrite) rewriteHosts(hosts []string, source, target, path string, changes *rewriteLog) ([]string, error) {
+	rewritten := make([]string, len(hosts))
+	for i, host := range hosts {
+		newHost, err := o.rewriteHost(host, source, target)
+		if err != nil {
+			return nil, err
+		}
+		rewritten[i] = newHost
+		if newHost != host {
+			changes.record(fmt.Sprintf("%s[%d]", path, i), host, newHost)
+		}
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,124 @@
+package managers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// ImageOverrides rewrite container images while cloning. Values are either a full image
+// ("registry.dev.io/app:v2") or only a tag prefixed with ":" (":v2").
+// A workload/container entry wins over a container entry, which wins over repository rules.
+type ImageOverrides struct {
+	// Container name to image, applied to every workload
+	Containers map[string]string `json:"containers"`
+	// "<workload>/<container>" to image, e.g. "frontend/app"
+	Workloads map[string]string `json:"workloads"`
+	// Rules matched in order against the image repository (the image without tag or digest)
+	Repositories []RepositoryOverride `json:"repositories"`
+}
+
+type RepositoryOverride struct {
+	// Regular expression matched against the whole repository, e.g. "registry.prod.io/(.*)"
+	Pattern string `json:"pattern"`
+	// Replacement repository, may reference groups from Pattern, e.g. "registry.dev.io/$1". The tag is kept.
+	Repository string `json:"repository"`
+	// Replacement tag
+	Tag string `json:"tag"`
+
+	// Pattern compiled by validate
+	pattern *regexp.Regexp
+}
+
+func (o *ImageOverrides) validate() *Error {
+	if o == nil {
+		return nil
+	}
+	for i := range o.Repositories {
+		rule := &o.Repositories[i]
+		pattern, err := compilePattern(rule.Pattern)
+		if err != nil {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid image repository pattern %q: %v", rule.Pattern, err),
+			}
+		}
+		rule.pattern = pattern
+		if rule.Repository == "" && rule.Tag == "" {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Image repository pattern %q needs a repository or a tag", rule.Pattern),
+			}
+		}
+	}
+	return nil
+}
+
+// splitImage splits an image into its repository and its tag or digest reference
+func splitImage(image string) (string, string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i], image[i:]
+	}
+	// A ":" before the last "/" is a registry port, not a tag
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i:]
+	}
+	return image, ""
+}
+
+// overrideImage applies a map value to an image, ":tag" only replaces the tag
+func overrideImage(image, value string) string {
+	if strings.HasPrefix(value, ":") {
+		repository, _ := splitImage(image)
+		return repository + value
+	}
+	return value
+}
+
+func (o *ImageOverrides) imageFor(workload, container, image string) string {
+	if value, ok := o.Workloads[workload+"/"+container]; ok {
+		return overrideImage(image, value)
+	}
+	if value, ok := o.Containers[container]; ok {
+		return overrideImage(image, value)
+	}
+	repository, reference := splitImage(image)
+	for _, rule := range o.Repositories {
+		if !rule.pattern.MatchString(repository) {
+			continue
+		}
+		if rule.Repository != "" {
+			repository = rule.pattern.ReplaceAllString(repository, rule.Repository)
+		}
+		if rule.Tag != "" {
+			reference = ":" + strings.TrimPrefix(rule.Tag, ":")
+		}
+		return repository + reference
+	}
+	return image
+}
+
+// applyImageOverrides rewrites the containers and init containers of a workload's pod spec in place.
+// The returned mutations describe every changed image for dry run plans, keyed below podSpecPath.
+func applyImageOverrides(workload, podSpecPath string, podSpec *v1.PodSpec, overrides *ImageOverrides) map[string]interface{} {
+	mutations := make(map[string]interface{})
+	if overrides == nil {
+		return mutations
+	}
+	for i, container := range podSpec.InitContainers {
+		if image := overrides.imageFor(workload, container.Name, container.Image); image != container.Image {
+			podSpec.InitContainers[i].Image = image
+			mutations[fmt.Sprintf("%s.initContainers[%s].image", podSpecPath, container.Name)] = image
+		}
+	}
+	for i, container := range podSpec.Containers {
+		if image := overrides.imageFor(workload, container.Name, container.Image); image != container.Image {
+			podSpec.Containers[i].Image = image
+			mutations[fmt.Sprintf("%s.containers[%s].image", podSpecPath, container.Name)] = image
+		}
+	}
+	return mutations
+}
@@ -0,0 +1,408 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// istioResource is an Istio kind cloned alongside VirtualServices. rewrite adjusts the references a
+// clone must not share with its source, recording every change in changes.
+type istioResource struct {
+	kind    string
+	gvr     schema.GroupVersionResource
+	rewrite func(item *unstructured.Unstructured, rewrite istioRewrite, changes *rewriteLog) error
+}
+
+// istioRewrite moves references from the source to the target namespace
+type istioRewrite struct {
+	source string
+	target string
+	hosts  *HostRewrite
+}
+
+var istioResources = []istioResource{
+	{
+		kind: "Gateway",
+		gvr:  schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1alpha3", Resource: "gateways"},
+		rewrite: func(item *unstructured.Unstructured, r istioRewrite, changes *rewriteLog) error {
+			// Gateway hosts are external hostnames, rewritten like VirtualService hosts
+			return rewriteStrings(item.Object, []string{"spec", "servers[]", "hosts[]"}, "", r.gatewayHost, changes)
+		},
+	},
+	{
+		kind: "DestinationRule",
+		gvr:  schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1alpha3", Resource: "destinationrules"},
+		rewrite: func(item *unstructured.Unstructured, r istioRewrite, changes *rewriteLog) error {
+			return rewriteStrings(item.Object, []string{"spec", "host"}, "", r.serviceHost, changes)
+		},
+	},
+	{
+		kind: "ServiceEntry",
+		gvr:  schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1alpha3", Resource: "serviceentries"},
+		rewrite: func(item *unstructured.Unstructured, r istioRewrite, changes *rewriteLog) error {
+			return rewriteStrings(item.Object, []string{"spec", "hosts[]"}, "", r.serviceHost, changes)
+		},
+	},
+	{
+		kind: "Sidecar",
+		gvr:  schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1alpha3", Resource: "sidecars"},
+		rewrite: func(item *unstructured.Unstructured, r istioRewrite, changes *rewriteLog) error {
+			return rewriteStrings(item.Object, []string{"spec", "egress[]", "hosts[]"}, "", r.namespacedHost, changes)
+		},
+	},
+	{
+		kind: "PeerAuthentication",
+		gvr:  schema.GroupVersionResource{Group: "security.istio.io", Version: "v1beta1", Resource: "peerauthentications"},
+	},
+	{
+		kind: "AuthorizationPolicy",
+		gvr:  schema.GroupVersionResource{Group: "security.istio.io", Version: "v1beta1", Resource: "authorizationpolicies"},
+		rewrite: func(item *unstructured.Unstructured, r istioRewrite, changes *rewriteLog) error {
+			for _, field := range []string{"namespaces[]", "notNamespaces[]"} {
+				if err := rewriteStrings(item.Object, []string{"spec", "rules[]", "from[]", "source", field}, "", r.namespace, changes); err != nil {
+					return err
+				}
+			}
+			for _, field := range []string{"principals[]", "notPrincipals[]"} {
+				if err := rewriteStrings(item.Object, []string{"spec", "rules[]", "from[]", "source", field}, "", r.principal, changes); err != nil {
+					return err
+				}
+			}
+			for _, field := range []string{"hosts[]", "notHosts[]"} {
+				if err := rewriteStrings(item.Object, []string{"spec", "rules[]", "to[]", "operation", field}, "", r.serviceHost, changes); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		kind: "RequestAuthentication",
+		gvr:  schema.GroupVersionResource{Group: "security.istio.io", Version: "v1beta1", Resource: "requestauthentications"},
+	},
+}
+
+// How references of a cloned VirtualService are rewritten
+const (
+	// Point the reference at the target namespace, the default
+	ReferenceRewriteTarget = "target"
+	// Leave the reference as it is in the source
+	ReferenceRewriteKeep = "keep"
+	// Only for exportTo, make the VirtualService visible in the target namespace only
+	ExportToPrivate = "private"
+)
+
+// VirtualServicePolicy decides which references of cloned VirtualServices move to the target namespace.
+// Hosts are rewritten by the clone's HostRewrite.
+type VirtualServicePolicy struct {
+	// Route destination and mirror hosts qualified with the source namespace: "target" or "keep"
+	Destinations string `json:"destinations"`
+	// Gateways referenced as "source/gateway" or by their source service host: "target" or "keep"
+	Gateways string `json:"gateways"`
+	// Gateways to replace, e.g. {"istio-system/public": "istio-system/dev"}. Applied before Gateways.
+	GatewayMap map[string]string `json:"gatewayMap"`
+	// The source namespace in exportTo: "target", "keep" or "private" to export to "." only
+	ExportTo string `json:"exportTo"`
+}
+
+// Fields of a VirtualService holding destination hosts
+var virtualServiceDestinationPaths = [][]string{
+	{"spec", "http[]", "route[]", "destination", "host"},
+	{"spec", "http[]", "mirror", "host"},
+	{"spec", "http[]", "mirrors[]", "destination", "host"},
+	{"spec", "tcp[]", "route[]", "destination", "host"},
+	{"spec", "tls[]", "route[]", "destination", "host"},
+}
+
+// Fields of a VirtualService holding gateway references
+var virtualServiceGatewayPaths = [][]string{
+	{"spec", "gateways[]"},
+	{"spec", "http[]", "match[]", "gateways[]"},
+	{"spec", "tcp[]", "match[]", "gateways[]"},
+	{"spec", "tls[]", "match[]", "gateways[]"},
+}
+
+func (p *VirtualServicePolicy) validate() *Error {
+	if p == nil {
+		return nil
+	}
+	for field, value := range map[string]string{"destinations": p.Destinations, "gateways": p.Gateways} {
+		if value != "" && value != ReferenceRewriteTarget && value != ReferenceRewriteKeep {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid virtualServices.%s %q, expected target or keep", field, value),
+			}
+		}
+	}
+	if p.ExportTo != "" && p.ExportTo != ReferenceRewriteTarget && p.ExportTo != ReferenceRewriteKeep && p.ExportTo != ExportToPrivate {
+		return &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Invalid virtualServices.exportTo %q, expected target, keep or private", p.ExportTo),
+		}
+	}
+	return nil
+}
+
+// rewrite applies the policy to a VirtualService being cloned, a nil policy rewrites everything to the target
+func (p *VirtualServicePolicy) rewrite(item *unstructured.Unstructured, r istioRewrite, changes *rewriteLog) error {
+	var policy VirtualServicePolicy
+	if p != nil {
+		policy = *p
+	}
+	if policy.Destinations != ReferenceRewriteKeep {
+		for _, path := range virtualServiceDestinationPaths {
+			if err := rewriteStrings(item.Object, path, "", r.serviceHost, changes); err != nil {
+				return err
+			}
+		}
+	}
+	gateway := func(ref string) (string, error) {
+		if replacement, ok := policy.GatewayMap[ref]; ok {
+			return replacement, nil
+		}
+		// The mesh pseudo gateway and short names, which resolve in the clone's own namespace, are kept
+		if policy.Gateways == ReferenceRewriteKeep || ref == "mesh" {
+			return ref, nil
+		}
+		return r.namespacedHost(ref)
+	}
+	for _, path := range virtualServiceGatewayPaths {
+		if err := rewriteStrings(item.Object, path, "", gateway, changes); err != nil {
+			return err
+		}
+	}
+	switch policy.ExportTo {
+	case ReferenceRewriteKeep:
+	case ExportToPrivate:
+		exportTo, _, _ := unstructured.NestedStringSlice(item.Object, "spec", "exportTo")
+		if len(exportTo) != 1 || exportTo[0] != "." {
+			if err := unstructured.SetNestedStringSlice(item.Object, []string{"."}, "spec", "exportTo"); err != nil {
+				return err
+			}
+			changes.record("spec.exportTo", strings.Join(exportTo, ","), ".")
+		}
+	default:
+		return rewriteStrings(item.Object, []string{"spec", "exportTo[]"}, "", r.namespace, changes)
+	}
+	return nil
+}
+
+// FieldRewrite is a single field of a cloned object changed to point at the clone instead of its source
+type FieldRewrite struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// rewriteLog collects the rewrites made to one object, both as dry run mutations and for the job report
+type rewriteLog struct {
+	kind      string
+	name      string
+	mutations map[string]interface{}
+	rewrites  []FieldRewrite
+}
+
+func newRewriteLog(kind, name string) *rewriteLog {
+	return &rewriteLog{kind: kind, name: name, mutations: make(map[string]interface{})}
+}
+
+func (l *rewriteLog) record(field, from, to string) {
+	l.mutations[field] = to
+	l.rewrites = append(l.rewrites, FieldRewrite{Kind: l.kind, Name: l.name, Field: field, From: from, To: to})
+}
+
+// rewriteServiceHost moves a namespace qualified service host such as "svc.source", "svc.source.svc" or
+// "svc.source.svc.cluster.local" to the target namespace. ok is false for any other host.
+func rewriteServiceHost(host, source, target string) (string, bool) {
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 || labels[1] != source || (len(labels) > 2 && labels[2] != "svc") {
+		return host, false
+	}
+	labels[1] = target
+	return strings.Join(labels, "."), true
+}
+
+func (r istioRewrite) serviceHost(host string) (string, error) {
+	rewritten, _ := rewriteServiceHost(host, r.source, r.target)
+	return rewritten, nil
+}
+
+func (r istioRewrite) namespace(namespace string) (string, error) {
+	if namespace == r.source {
+		return r.target, nil
+	}
+	return namespace, nil
+}
+
+// namespacedHost rewrites "namespace/host" references, e.g. Sidecar egress hosts
+func (r istioRewrite) namespacedHost(ref string) (string, error) {
+	namespace, host, ok := strings.Cut(ref, "/")
+	if !ok {
+		return r.serviceHost(ref)
+	}
+	namespace, _ = r.namespace(namespace)
+	host, _ = r.serviceHost(host)
+	return namespace + "/" + host, nil
+}
+
+// gatewayHost rewrites Gateway server hosts, optionally qualified with a namespace as "namespace/host"
+func (r istioRewrite) gatewayHost(ref string) (string, error) {
+	namespace, host, ok := strings.Cut(ref, "/")
+	if !ok {
+		return r.hosts.rewriteHost(ref, r.source, r.target)
+	}
+	namespace, _ = r.namespace(namespace)
+	host, err := r.hosts.rewriteHost(host, r.source, r.target)
+	return namespace + "/" + host, err
+}
+
+// principal rewrites SPIFFE style principals like "cluster.local/ns/source/sa/name"
+func (r istioRewrite) principal(principal string) (string, error) {
+	return strings.Replace(principal, "/ns/"+r.source+"/", "/ns/"+r.target+"/", 1), nil
+}
+
+// rewriteStrings rewrites the strings found at path in an unstructured object. A path element ending with
+// "[]" is a list whose items are all visited. Changes are recorded with their full field path.
+func rewriteStrings(obj map[string]interface{}, path []string, prefix string, rewrite func(string) (string, error), changes *rewriteLog) error {
+	field := path[0]
+	isList := strings.HasSuffix(field, "[]")
+	field = strings.TrimSuffix(field, "[]")
+	fieldPath := strings.TrimPrefix(prefix+"."+field, ".")
+	value, ok := obj[field]
+	if !ok {
+		return nil
+	}
+	visit := func(value interface{}, valuePath string) (interface{}, error) {
+		if len(path) == 1 {
+			s, ok := value.(string)
+			if !ok {
+				return value, nil
+			}
+			rewritten, err := rewrite(s)
+			if err != nil {
+				return nil, err
+			}
+			if rewritten != s {
+				changes.record(valuePath, s, rewritten)
+			}
+			return rewritten, nil
+		}
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return value, nil
+		}
+		return nested, rewriteStrings(nested, path[1:], valuePath, rewrite, changes)
+	}
+	if !isList {
+		rewritten, err := visit(value, fieldPath)
+		if err != nil {
+			return err
+		}
+		obj[field] = rewritten
+		return nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	for i, item := range items {
+		rewritten, err := visit(item, fmt.Sprintf("%s[%d]", fieldPath, i))
+		if err != nil {
+			return err
+		}
+		items[i] = rewritten
+	}
+	return nil
+}
+
+// cloneIstioResource clones the objects of an Istio kind. Kinds whose CRD isn't installed are skipped.
+func cloneIstioResource(dynamicClient dynamic.Interface, resource istioResource, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetDynamicClient := job.targetDynamicClient(dynamicClient)
+	items, err := dynamicClient.Resource(resource.gvr).Namespace(sourceNamespace).List(context.TODO(), job.listOptions())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The CRD isn't installed in the source cluster, there is nothing to clone
+			log.Printf("Skipping %s, %s is not installed\n", resource.kind, resource.gvr.GroupResource().String())
+			return nil
+		}
+		log.Printf("Error checking for %s: %v\n", resource.kind, err)
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	if len(items.Items) > 0 && job.crossCluster() {
+		if _, err := targetDynamicClient.Resource(resource.gvr).Namespace(targetNamespace).List(context.TODO(), metav1.ListOptions{Limit: 1}); errors.IsNotFound(err) {
+			for _, item := range items.Items {
+				job.skip(resource.kind, item.GetName(), fmt.Sprintf("%s is not installed in the target cluster", resource.gvr.GroupResource().String()))
+			}
+			return nil
+		}
+	}
+
+	rewrite := istioRewrite{source: sourceNamespace, target: targetNamespace, hosts: job.hostRewrite()}
+	for _, item := range items.Items {
+		if !job.matchesName(item.GetName()) {
+			continue
+		}
+		_, err := targetDynamicClient.Resource(resource.gvr).Namespace(targetNamespace).Get(context.TODO(), item.GetName(), metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error checking for existing %s %s: %v", resource.kind, item.GetName(), err),
+			}
+		} else if err == nil {
+			log.Printf("%s %s already exists in %s, skipping creation\n", resource.kind, item.GetName(), targetNamespace)
+			job.skip(resource.kind, item.GetName(), "already exists")
+			continue
+		}
+
+		sanitizeForClone(&item, targetNamespace)
+		annotations := item.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		delete(annotations, lastAppliedConfigAnnotation)
+		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+		annotations[TARGET_OBJECT_ANNOTATION] = resource.gvr.GroupResource().String() + "/" + item.GetName()
+		item.SetAnnotations(annotations)
+
+		changes := newRewriteLog(resource.kind, item.GetName())
+		if resource.rewrite != nil {
+			if err := resource.rewrite(&item, rewrite, changes); err != nil {
+				return &Error{
+					Code:    http.StatusBadRequest,
+					Message: fmt.Sprintf("Error rewriting %s %s: %v", resource.kind, item.GetName(), err),
+				}
+			}
+		}
+
+		newObject := item.DeepCopy()
+		created, errObj := job.createResource(resource.gvr, resource.kind, item.GetName(), annotations, changes.mutations, func(opts metav1.CreateOptions) error {
+			_, err := targetDynamicClient.Resource(resource.gvr).Namespace(targetNamespace).Create(context.TODO(), newObject, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		job.recordRewrites(changes.rewrites)
+		if !created {
+			continue
+		}
+		log.Printf("%s %s cloned successfully to namespace %s\n", resource.kind, item.GetName(), targetNamespace)
+		job.record(resource.kind, item.GetName(), ObjectStatusReady, "")
+	}
+	return nil
+}
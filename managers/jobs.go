@@ -0,0 +1,654 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+type JobPhase string
+
+const (
+	JobPhasePending   JobPhase = "Pending"
+	JobPhaseRunning   JobPhase = "Running"
+	JobPhaseSucceeded JobPhase = "Succeeded"
+	JobPhaseFailed    JobPhase = "Failed"
+	JobPhaseAborted   JobPhase = "Aborted"
+)
+
+// Per-object outcomes reported on a clone job
+const (
+	ObjectStatusCreated = "Created"
+	ObjectStatusReady   = "Ready"
+	ObjectStatusSkipped = "Skipped"
+	ObjectStatusFailed  = "Failed"
+)
+
+type ObjectProgress struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+
+	// Set once the job created the object, kept when it later fails its readiness checks
+	created bool
+	// Resource the object was created as
+	resource schema.GroupVersionResource
+}
+
+type CloneJobStatus struct {
+	ID              string           `json:"id"`
+	SourceNamespace string           `json:"sourceNamespace"`
+	TargetNamespace string           `json:"targetNamespace"`
+	Phase           JobPhase         `json:"phase"`
+	CurrentKind     string           `json:"currentKind,omitempty"`
+	Objects         []ObjectProgress `json:"objects"`
+	Plan            []PlannedObject  `json:"plan,omitempty"`
+	// Every field rewritten to point at the clone instead of the source
+	Rewrites []FieldRewrite `json:"rewrites,omitempty"`
+	// What the secret policy did with each Secret
+	Secrets   []SecretOutcome `json:"secrets,omitempty"`
+	StartTime *time.Time      `json:"startTime,omitempty"`
+	EndTime   *time.Time      `json:"endTime,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// CloneJob tracks a single CloneNamespace run. All methods are safe to call on a nil job
+// so the Clone* functions can still be used outside of a job.
+type CloneJob struct {
+	ID              string
+	SourceNamespace string
+	TargetNamespace string
+	Options         CloneOptions
+
+	nameRegex *regexp.Regexp
+	// Clients for the target cluster, nil when cloning within the source cluster
+	target *ClusterClients
+	// When the cloned namespace expires, nil when it is kept until deleted
+	expiresAt *time.Time
+
+	// Cancelled when the job is aborted or, for jobs run while the client waits, when the client disconnects
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu          sync.RWMutex
+	phase       JobPhase
+	currentKind string
+	objects     []ObjectProgress
+	plan        []PlannedObject
+	rewrites    []FieldRewrite
+	secrets     []SecretOutcome
+	// Set for dry runs when the target namespace does not exist yet, namespaced objects can't be
+	// validated by the API server in that case
+	targetMissing bool
+	startTime     *time.Time
+	endTime       *time.Time
+	err           string
+}
+
+var cloneJobs = struct {
+	sync.RWMutex
+	jobs map[string]*CloneJob
+}{jobs: make(map[string]*CloneJob)}
+
+// How long finished clone and deletion jobs are kept for status requests. Overridable with -job-retention.
+var JobRetention = 24 * time.Hour
+
+// pruneCloneJobs forgets jobs that finished more than JobRetention ago. Must be called with cloneJobs locked.
+func pruneCloneJobs(now time.Time) {
+	for id, job := range cloneJobs.jobs {
+		job.mu.RLock()
+		endTime := job.endTime
+		job.mu.RUnlock()
+		if endTime != nil && now.Sub(*endTime) > JobRetention {
+			delete(cloneJobs.jobs, id)
+		}
+	}
+}
+
+// NewCloneJob validates the options and registers a pending job. The job is cancelled along with ctx.
+func NewCloneJob(ctx context.Context, sourceNamespace, targetNamespace string, options CloneOptions) (*CloneJob, *Error) {
+	if errObj := options.Validate(); errObj != nil {
+		return nil, errObj
+	}
+	if options.ServerDryRun {
+		options.DryRun = true
+	}
+	job := &CloneJob{
+		ID:              rand.String(10),
+		SourceNamespace: sourceNamespace,
+		TargetNamespace: targetNamespace,
+		Options:         options,
+		phase:           JobPhasePending,
+		objects:         []ObjectProgress{},
+	}
+	job.ctx, job.cancel = context.WithCancel(ctx)
+	if options.NameRegex != "" {
+		job.nameRegex = regexp.MustCompile(options.NameRegex)
+	}
+	job.expiresAt, _ = parseExpiry(options.TTL, options.ExpiresAt, time.Now())
+	if options.TargetCluster != "" {
+		target, errObj := GetCluster(options.TargetCluster)
+		if errObj != nil {
+			return nil, errObj
+		}
+		job.target = target
+	}
+	cloneJobs.Lock()
+	pruneCloneJobs(time.Now())
+	cloneJobs.jobs[job.ID] = job
+	cloneJobs.Unlock()
+	return job, nil
+}
+
+func GetCloneJob(id string) (CloneJobStatus, *Error) {
+	cloneJobs.RLock()
+	job, ok := cloneJobs.jobs[id]
+	cloneJobs.RUnlock()
+	if !ok {
+		return CloneJobStatus{}, &Error{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("Clone job %s not found", id),
+		}
+	}
+	return job.Status(), nil
+}
+
+// AbortCloneJob cancels a running job. Waits for readiness stop right away and the target namespace is
+// removed like after any other failure.
+func AbortCloneJob(id string) (CloneJobStatus, *Error) {
+	cloneJobs.RLock()
+	job, ok := cloneJobs.jobs[id]
+	cloneJobs.RUnlock()
+	if !ok {
+		return CloneJobStatus{}, &Error{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("Clone job %s not found", id),
+		}
+	}
+	status := job.Status()
+	if status.Phase != JobPhasePending && status.Phase != JobPhaseRunning {
+		return status, &Error{
+			Code:    http.StatusConflict,
+			Message: fmt.Sprintf("Clone job %s already finished", id),
+		}
+	}
+	log.Printf("Aborting clone job %s\n", id)
+	job.cancel()
+	return job.Status(), nil
+}
+
+func ListCloneJobs() []CloneJobStatus {
+	cloneJobs.Lock()
+	pruneCloneJobs(time.Now())
+	statuses := make([]CloneJobStatus, 0, len(cloneJobs.jobs))
+	for _, job := range cloneJobs.jobs {
+		statuses = append(statuses, job.Status())
+	}
+	cloneJobs.Unlock()
+	// Most recent jobs first, pending jobs (no start time yet) on top
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].StartTime == nil || statuses[j].StartTime == nil {
+			return statuses[i].StartTime == nil && statuses[j].StartTime != nil
+		}
+		return statuses[i].StartTime.After(*statuses[j].StartTime)
+	})
+	return statuses
+}
+
+// RunCloneJob runs CloneNamespace for the job and records the outcome. Meant to be run in a go routine.
+func RunCloneJob(clientset *kubernetes.Clientset, dynamicClientSet *dynamic.DynamicClient, job *CloneJob) {
+	job.start()
+	errObj := CloneNamespace(clientset, dynamicClientSet, job.SourceNamespace, job.TargetNamespace, job)
+	if errObj != nil {
+		log.Printf("Clone job %s failed: %s\n", job.ID, errObj.Message)
+	} else {
+		log.Printf("Clone job %s completed: %s cloned to %s\n", job.ID, job.SourceNamespace, job.TargetNamespace)
+	}
+	job.finish(errObj)
+	job.cancel()
+}
+
+func (j *CloneJob) Status() CloneJobStatus {
+	if j == nil {
+		return CloneJobStatus{}
+	}
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	objects := make([]ObjectProgress, len(j.objects))
+	copy(objects, j.objects)
+	var plan []PlannedObject
+	if j.plan != nil {
+		plan = make([]PlannedObject, len(j.plan))
+		copy(plan, j.plan)
+	}
+	return CloneJobStatus{
+		ID:              j.ID,
+		SourceNamespace: j.SourceNamespace,
+		TargetNamespace: j.TargetNamespace,
+		Phase:           j.phase,
+		CurrentKind:     j.currentKind,
+		Objects:         objects,
+		Plan:            plan,
+		Rewrites:        append([]FieldRewrite(nil), j.rewrites...),
+		Secrets:         append([]SecretOutcome(nil), j.secrets...),
+		StartTime:       j.startTime,
+		EndTime:         j.endTime,
+		Error:           j.err,
+	}
+}
+
+func (j *CloneJob) start() {
+	if j == nil {
+		return
+	}
+	now := time.Now()
+	j.mu.Lock()
+	j.phase = JobPhaseRunning
+	j.startTime = &now
+	j.mu.Unlock()
+}
+
+func (j *CloneJob) finish(errObj *Error) {
+	if j == nil {
+		return
+	}
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.endTime = &now
+	j.currentKind = ""
+	if errObj != nil {
+		j.phase = JobPhaseFailed
+		if j.context().Err() != nil {
+			j.phase = JobPhaseAborted
+		}
+		j.err = errObj.Message
+		return
+	}
+	j.phase = JobPhaseSucceeded
+}
+
+func (j *CloneJob) setCurrentKind(kind string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	j.currentKind = kind
+	j.mu.Unlock()
+}
+
+// record stores the latest status of an object, replacing an earlier entry for the same object
+func (j *CloneJob) record(kind, name, status, message string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.objects {
+		if j.objects[i].Kind == kind && j.objects[i].Name == name {
+			j.objects[i].Status = status
+			j.objects[i].Message = message
+			return
+		}
+	}
+	j.objects = append(j.objects, ObjectProgress{Kind: kind, Name: name, Status: status, Message: message})
+}
+
+// markCreated remembers that the job created an object as resource, which a failed clone rolls back
+func (j *CloneJob) markCreated(kind, name string, resource schema.GroupVersionResource) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.objects {
+		if j.objects[i].Kind == kind && j.objects[i].Name == name {
+			j.objects[i].created = true
+			j.objects[i].resource = resource
+			return
+		}
+	}
+}
+
+func (j *CloneJob) recordRewrites(rewrites []FieldRewrite) {
+	if j == nil || len(rewrites) == 0 {
+		return
+	}
+	j.mu.Lock()
+	j.rewrites = append(j.rewrites, rewrites...)
+	j.mu.Unlock()
+}
+
+// recordSecret stores the outcome for a Secret, replacing an earlier outcome for the same Secret
+func (j *CloneJob) recordSecret(outcome SecretOutcome) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.secrets {
+		if j.secrets[i].Name == outcome.Name {
+			j.secrets[i] = outcome
+			return
+		}
+	}
+	j.secrets = append(j.secrets, outcome)
+}
+
+func (j *CloneJob) markTargetMissing() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	j.targetMissing = true
+	j.mu.Unlock()
+}
+
+func (j *CloneJob) dryRun() bool {
+	return j != nil && j.Options.DryRun
+}
+
+// skip records an object that is not cloned, along with the reason
+func (j *CloneJob) skip(kind, name, reason string) {
+	if j == nil {
+		return
+	}
+	j.record(kind, name, ObjectStatusSkipped, reason)
+	if j.Options.DryRun {
+		j.mu.Lock()
+		j.plan = append(j.plan, PlannedObject{Kind: kind, Name: name, Action: PlanActionSkip, Reason: reason})
+		j.mu.Unlock()
+	}
+}
+
+// plans tells whether a dry run plans to create an object
+func (j *CloneJob) plans(kind, name string) bool {
+	if j == nil {
+		return false
+	}
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	for _, planned := range j.plan {
+		if planned.Kind == kind && planned.Name == name && planned.Action == PlanActionCreate {
+			return true
+		}
+	}
+	return false
+}
+
+// planPatch adds a change to an object outside of the target namespace to a dry run plan
+func (j *CloneJob) planPatch(kind, name, reason string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	j.plan = append(j.plan, PlannedObject{Kind: kind, Name: name, Action: PlanActionPatch, Reason: reason})
+	j.mu.Unlock()
+}
+
+// Resources of the kinds created through the typed clientset, by kind
+var typedResources = map[string]schema.GroupVersionResource{
+	"Namespace":               {Version: "v1", Resource: "namespaces"},
+	"ConfigMap":               {Version: "v1", Resource: "configmaps"},
+	"Secret":                  {Version: "v1", Resource: "secrets"},
+	"Service":                 {Version: "v1", Resource: "services"},
+	"ServiceAccount":          {Version: "v1", Resource: "serviceaccounts"},
+	"ResourceQuota":           {Version: "v1", Resource: "resourcequotas"},
+	"LimitRange":              {Version: "v1", Resource: "limitranges"},
+	"PersistentVolumeClaim":   {Version: "v1", Resource: "persistentvolumeclaims"},
+	"Deployment":              {Group: "apps", Version: "v1", Resource: "deployments"},
+	"StatefulSet":             {Group: "apps", Version: "v1", Resource: "statefulsets"},
+	"Job":                     {Group: "batch", Version: "v1", Resource: "jobs"},
+	"CronJob":                 {Group: "batch", Version: "v1beta1", Resource: "cronjobs"},
+	"Ingress":                 {Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+	"NetworkPolicy":           {Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+	"PodDisruptionBudget":     {Group: "policy", Version: "v1beta1", Resource: "poddisruptionbudgets"},
+	"HorizontalPodAutoscaler": {Group: "autoscaling", Version: "v1", Resource: "horizontalpodautoscalers"},
+	"Role":                    {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"},
+	"RoleBinding":             {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"},
+}
+
+// create runs the Create call for an object of a kind in typedResources, see createResource
+func (j *CloneJob) create(kind, name string, annotations map[string]string, mutations map[string]interface{}, create func(metav1.CreateOptions) error) (bool, *Error) {
+	return j.createResource(typedResources[kind], kind, name, annotations, mutations, create)
+}
+
+// createResource runs the Create call for a cloned object of resource. On a dry run the object is only added
+// to the plan (validated by the API server when ServerDryRun is set) and created is false, so callers skip any
+// readiness checks for it.
+func (j *CloneJob) createResource(resource schema.GroupVersionResource, kind, name string, annotations map[string]string, mutations map[string]interface{}, create func(metav1.CreateOptions) error) (bool, *Error) {
+	if j.dryRun() {
+		planned := PlannedObject{
+			Kind:        kind,
+			Name:        name,
+			Action:      PlanActionCreate,
+			Annotations: annotations,
+			Mutations:   mutations,
+		}
+		if j.Options.ServerDryRun {
+			j.mu.RLock()
+			targetMissing := j.targetMissing
+			j.mu.RUnlock()
+			if targetMissing && kind != "Namespace" {
+				planned.ServerDryRun = ServerDryRunSkipped
+				planned.ServerDryRunMessage = fmt.Sprintf("namespace %s does not exist yet", j.TargetNamespace)
+			} else if err := create(metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}}); err != nil {
+				planned.ServerDryRun = ServerDryRunFailed
+				planned.ServerDryRunMessage = err.Error()
+			} else {
+				planned.ServerDryRun = ServerDryRunPassed
+			}
+		}
+		j.mu.Lock()
+		j.plan = append(j.plan, planned)
+		j.mu.Unlock()
+		return false, nil
+	}
+
+	if err := create(metav1.CreateOptions{}); err != nil {
+		j.record(kind, name, ObjectStatusFailed, err.Error())
+		return false, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	j.record(kind, name, ObjectStatusCreated, "")
+	j.markCreated(kind, name, resource)
+	return true, nil
+}
+
+func (j *CloneJob) listOptions() metav1.ListOptions {
+	if j == nil {
+		return metav1.ListOptions{}
+	}
+	return metav1.ListOptions{LabelSelector: j.Options.LabelSelector}
+}
+
+func (j *CloneJob) matchesName(name string) bool {
+	if j == nil || j.nameRegex == nil {
+		return true
+	}
+	return j.nameRegex.MatchString(name)
+}
+
+// selects tells whether an object passes the job's label selector and name filters
+func (j *CloneJob) selects(object metav1.Object) bool {
+	if !j.matchesName(object.GetName()) {
+		return false
+	}
+	if j == nil || j.Options.LabelSelector == "" {
+		return true
+	}
+	selector, err := labels.Parse(j.Options.LabelSelector)
+	return err == nil && selector.Matches(labels.Set(object.GetLabels()))
+}
+
+// includesKind applies the IncludeKinds and ExcludeKinds filters, kinds are matched case insensitively
+func (j *CloneJob) includesKind(kind string) bool {
+	if j == nil {
+		return true
+	}
+	for _, excluded := range j.Options.ExcludeKinds {
+		if strings.EqualFold(excluded, kind) {
+			return false
+		}
+	}
+	if len(j.Options.IncludeKinds) == 0 {
+		return true
+	}
+	for _, included := range j.Options.IncludeKinds {
+		if strings.EqualFold(included, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *CloneJob) imageOverrides() *ImageOverrides {
+	if j == nil {
+		return nil
+	}
+	return j.Options.Images
+}
+
+func (j *CloneJob) hostRewrite() *HostRewrite {
+	if j == nil {
+		return nil
+	}
+	return j.Options.Hosts
+}
+
+func (j *CloneJob) virtualServicePolicy() *VirtualServicePolicy {
+	if j == nil {
+		return nil
+	}
+	return j.Options.VirtualServices
+}
+
+func (j *CloneJob) headerRouting() *HeaderRouting {
+	if j == nil {
+		return nil
+	}
+	return j.Options.HeaderRouting
+}
+
+func (j *CloneJob) networkIsolation() *NetworkIsolation {
+	if j == nil {
+		return nil
+	}
+	return j.Options.NetworkIsolation
+}
+
+func (j *CloneJob) quotaProfile() string {
+	if j == nil {
+		return ""
+	}
+	return j.Options.QuotaProfile
+}
+
+func (j *CloneJob) volumeCloning() *VolumeCloning {
+	if j == nil {
+		return nil
+	}
+	return j.Options.Volumes
+}
+
+func (j *CloneJob) statefulSetOverrides() *StatefulSetOverrides {
+	if j == nil {
+		return nil
+	}
+	return j.Options.StatefulSets
+}
+
+func (j *CloneJob) secretPolicy() *SecretPolicy {
+	if j == nil {
+		return nil
+	}
+	return j.Options.Secrets
+}
+
+// context is cancelled when the job is aborted
+func (j *CloneJob) context() context.Context {
+	if j == nil || j.ctx == nil {
+		return context.TODO()
+	}
+	return j.ctx
+}
+
+// aborted is checked between clone steps so an aborted job stops before creating more objects
+func (j *CloneJob) aborted() *Error {
+	if j.context().Err() == nil {
+		return nil
+	}
+	return &Error{
+		Code:    http.StatusConflict,
+		Message: fmt.Sprintf("Clone job %s was aborted", j.ID),
+	}
+}
+
+func (j *CloneJob) readinessPolicy() ReadinessPolicy {
+	if j == nil || j.Options.Readiness == "" {
+		return ReadinessReady
+	}
+	return j.Options.Readiness
+}
+
+// readinessTimeout is the job's timeout for a kind, falling back to DefaultReadinessTimeouts
+func (j *CloneJob) readinessTimeout(kind string) time.Duration {
+	if j != nil {
+		for k, value := range j.Options.ReadinessTimeouts {
+			if timeout, err := time.ParseDuration(value); err == nil && strings.EqualFold(k, kind) {
+				return timeout
+			}
+		}
+	}
+	if timeout, ok := DefaultReadinessTimeouts[kind]; ok {
+		return timeout
+	}
+	return 5 * time.Minute
+}
+
+func (j *CloneJob) zeroReplicas() bool {
+	return j != nil && j.Options.ZeroReplicas
+}
+
+func (j *CloneJob) expiry() *time.Time {
+	if j == nil {
+		return nil
+	}
+	return j.expiresAt
+}
+
+// crossCluster is true when the job clones into a different cluster than the source
+func (j *CloneJob) crossCluster() bool {
+	return j != nil && j.target != nil
+}
+
+// targetClientset returns the clientset objects are cloned into, the source clientset unless the job has a target cluster
+func (j *CloneJob) targetClientset(clientset *kubernetes.Clientset) *kubernetes.Clientset {
+	if !j.crossCluster() {
+		return clientset
+	}
+	return j.target.Clientset
+}
+
+func (j *CloneJob) targetDynamicClient(dynamicClient dynamic.Interface) dynamic.Interface {
+	if !j.crossCluster() {
+		return dynamicClient
+	}
+	return j.target.DynamicClient
+}
@@ -2,6 +2,7 @@ package managers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,11 +11,17 @@ import (
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
@@ -22,9 +29,9 @@ import (
 
 var ClonedServiceTypes = []v1.ServiceType{corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort, corev1.ServiceTypeExternalName}
 
-func getconfigmapforNS(clientset *kubernetes.Clientset, namespace string) (*v1.ConfigMapList, *Error) {
+func getconfigmapforNS(clientset *kubernetes.Clientset, namespace string, listOptions metav1.ListOptions) (*v1.ConfigMapList, *Error) {
 	var configMaps *v1.ConfigMapList
-	configMaps, err := clientset.CoreV1().ConfigMaps(namespace).List(context.TODO(), metav1.ListOptions{})
+	configMaps, err := clientset.CoreV1().ConfigMaps(namespace).List(context.TODO(), listOptions)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Namespace doesn't have CronJobs, return successfully
@@ -33,7 +40,7 @@ func getconfigmapforNS(clientset *kubernetes.Clientset, namespace string) (*v1.C
 			return nil, nil
 		} else {
 			// Error checking for CronJobs
-			log.Printf("Error checking for ConfigMaps:", err)
+			log.Printf("Error checking for ConfigMaps: %v\n", err)
 			return configMaps, &Error{
 				Code:    http.StatusInternalServerError,
 				Message: err.Error(),
@@ -43,13 +50,17 @@ func getconfigmapforNS(clientset *kubernetes.Clientset, namespace string) (*v1.C
 	return configMaps, nil
 }
 
-func CloneConfigMap(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) *Error {
-	configMaps, err := getconfigmapforNS(clientset, sourceNamespace)
+func CloneConfigMap(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	configMaps, err := getconfigmapforNS(clientset, sourceNamespace, job.listOptions())
 	if err != nil {
 		return err
 	}
 	for _, configMap := range configMaps.Items {
-		_, err := clientset.CoreV1().ConfigMaps(targetNamespace).Get(context.TODO(), configMap.Name, metav1.GetOptions{})
+		if !job.matchesName(configMap.Name) {
+			continue
+		}
+		_, err := targetClientset.CoreV1().ConfigMaps(targetNamespace).Get(context.TODO(), configMap.Name, metav1.GetOptions{})
 		if err != nil && !errors.IsNotFound(err) {
 			// Handle unexpected errors
 			log.Printf("Error checking for existing configmap %s: %v\n", configMap.Name, err)
@@ -60,29 +71,33 @@ func CloneConfigMap(clientset *kubernetes.Clientset, sourceNamespace, targetName
 		} else if err == nil {
 			// ConfigMap already exists, skip creation
 			log.Printf("ConfigMap %s already exists in %s, skipping creation\n", configMap.Name, targetNamespace)
+			job.skip("ConfigMap", configMap.Name, "already exists")
 			continue
 		}
 		annotations := make(map[string]string)
 		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
 		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
 		annotations[TARGET_CM_ANNOTATION] = configMap.Name
-		_, err = clientset.CoreV1().ConfigMaps(targetNamespace).Create(context.TODO(), &v1.ConfigMap{
+		newConfigMap := &v1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:        configMap.Name,
 				Annotations: annotations,
 				Namespace:   targetNamespace,
 			},
 			Data: configMap.Data,
-		}, metav1.CreateOptions{})
-
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
-			}
+		}
+		created, errObj := job.create("ConfigMap", configMap.Name, annotations, nil, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.CoreV1().ConfigMaps(targetNamespace).Create(context.TODO(), newConfigMap, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		if !created {
+			continue
 		}
 		// Check if ConfigMap exists
-		_, err = clientset.CoreV1().ConfigMaps(targetNamespace).Get(context.TODO(), configMap.Name, metav1.GetOptions{})
+		_, err = targetClientset.CoreV1().ConfigMaps(targetNamespace).Get(context.TODO(), configMap.Name, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
 				return &Error{
@@ -99,13 +114,14 @@ func CloneConfigMap(clientset *kubernetes.Clientset, sourceNamespace, targetName
 
 		// ConfigMap exists, return success immediately (no status to check)
 		log.Printf("ConfigMap %s is ready\n", configMap.Name)
+		job.record("ConfigMap", configMap.Name, ObjectStatusReady, "")
 	}
 	return nil
 }
 
-func getSecretsforNS(clientset *kubernetes.Clientset, namespace string) (*v1.SecretList, *Error) {
+func getSecretsforNS(clientset *kubernetes.Clientset, namespace string, listOptions metav1.ListOptions) (*v1.SecretList, *Error) {
 	var secrets *v1.SecretList
-	secrets, err := clientset.CoreV1().Secrets(namespace).List(context.TODO(), metav1.ListOptions{})
+	secrets, err := clientset.CoreV1().Secrets(namespace).List(context.TODO(), listOptions)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Namespace doesn't have CronJobs, return successfully
@@ -124,69 +140,120 @@ func getSecretsforNS(clientset *kubernetes.Clientset, namespace string) (*v1.Sec
 	return secrets, nil
 }
 
-func CloneSecret(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) *Error {
-	secrets, err := getSecretsforNS(clientset, sourceNamespace)
+func CloneSecret(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	secrets, err := getSecretsforNS(clientset, sourceNamespace, job.listOptions())
 	if err != nil {
 		return err
 	}
-	for _, secret := range secrets.Items {
-		_, err := clientset.CoreV1().Secrets(targetNamespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
-		if err != nil && !errors.IsNotFound(err) {
-			// Handle unexpected errors
-			log.Printf("Error checking for existing secret %s: %v\n", secret.Name, err)
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
-			}
-		} else if err == nil {
-			// Secret already exists, skip creation
-			log.Printf("Secret %s already exists in %s, skipping creation\n", secret.Name, targetNamespace)
+	for i := range secrets.Items {
+		if !job.matchesName(secrets.Items[i].Name) {
 			continue
 		}
+		if errObj := cloneSecret(targetClientset, &secrets.Items[i], sourceNamespace, targetNamespace, job); errObj != nil {
+			return errObj
+		}
+	}
+	return nil
+}
 
-		annotations := make(map[string]string)
-		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
-		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
-		annotations[TARGET_SECRET_ANNOTATION] = secret.Name
-		_, err = clientset.CoreV1().Secrets(targetNamespace).Create(context.TODO(), &v1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:        secret.Name,
-				Namespace:   targetNamespace,
-				Annotations: annotations,
-			},
-			Data: secret.Data,
-		}, metav1.CreateOptions{})
-		if err != nil {
+// cloneSecret clones a single Secret of the source namespace
+func cloneSecret(targetClientset *kubernetes.Clientset, secret *v1.Secret, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	policy := job.secretPolicy()
+	rule := policy.rule(secret)
+	if rule.Action == SecretActionSkip {
+		reason := rule.Reason
+		if reason == "" {
+			reason = "skipped by secret policy"
+		}
+		log.Printf("Skipping Secret %s: %s\n", secret.Name, reason)
+		job.skip("Secret", secret.Name, reason)
+		job.recordSecret(SecretOutcome{Name: secret.Name, Type: secret.Type, Action: SecretActionSkip, Reason: reason})
+		return nil
+	}
+	_, err := targetClientset.CoreV1().Secrets(targetNamespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		// Handle unexpected errors
+		log.Printf("Error checking for existing secret %s: %v\n", secret.Name, err)
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	} else if err == nil {
+		// Secret already exists, skip creation
+		log.Printf("Secret %s already exists in %s, skipping creation\n", secret.Name, targetNamespace)
+		job.skip("Secret", secret.Name, "already exists")
+		job.recordSecret(SecretOutcome{Name: secret.Name, Type: secret.Type, Action: SecretActionSkip, Reason: "already exists"})
+		return nil
+	}
+
+	data, keys, errObj := policy.data(rule, secret)
+	if errObj != nil {
+		job.record("Secret", secret.Name, ObjectStatusFailed, errObj.Message)
+		return errObj
+	}
+	annotations := make(map[string]string)
+	annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+	annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+	annotations[TARGET_SECRET_ANNOTATION] = secret.Name
+	annotations[TARGET_SECRET_ACTION_ANNOTATION] = string(rule.Action)
+	mutations := make(map[string]interface{})
+	if len(keys) > 0 {
+		// Regenerated and substituted values are kept by a resync, values never show up in a plan
+		overrides := make([]string, len(keys))
+		for i, key := range keys {
+			overrides[i] = dataOverride(key)
+			mutations[dataOverride(key)] = "<" + string(rule.Action) + "d>"
+		}
+		annotations[TARGET_OVERRIDES_ANNOTATION] = encodeOverrides(overrides)
+	}
+	newSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secret.Name,
+			Namespace:   targetNamespace,
+			Annotations: annotations,
+		},
+		// TLS and pull secrets are only used with their type
+		Type: secret.Type,
+		Data: data,
+	}
+	job.recordSecret(SecretOutcome{Name: secret.Name, Type: secret.Type, Action: rule.Action, Keys: keys})
+	created, errObj := job.create("Secret", secret.Name, annotations, mutations, func(opts metav1.CreateOptions) error {
+		_, err := targetClientset.CoreV1().Secrets(targetNamespace).Create(context.TODO(), newSecret, opts)
+		return err
+	})
+	if errObj != nil || !created {
+		return errObj
+	}
+	// Check if Secret exists
+	_, err = targetClientset.CoreV1().Secrets(targetNamespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
 			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Secret %s not found in namespace %s", secret.Name, targetNamespace),
 			}
-		}
-		// Check if Secret exists
-		_, err = clientset.CoreV1().Secrets(targetNamespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
-		if err != nil {
-			if errors.IsNotFound(err) {
-				return &Error{
-					Code:    http.StatusBadRequest,
-					Message: fmt.Sprintf("Secret %s not found in namespace %s", secret.Name, targetNamespace),
-				}
-			} else {
-				return &Error{
-					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("Error checking for Secret %s: %v", secret.Name, err),
-				}
+		} else {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error checking for Secret %s: %v", secret.Name, err),
 			}
 		}
+	}
 
-		// Secret exists, return success immediately (no status to check)
-		log.Printf("Secret %s is ready\n", secret.Name)
+	// Secret exists, return success immediately (no status to check)
+	message := string(rule.Action)
+	if len(keys) > 0 {
+		message = fmt.Sprintf("%s %s", rule.Action, strings.Join(keys, ", "))
 	}
+	log.Printf("Secret %s is ready (%s)\n", secret.Name, message)
+	job.record("Secret", secret.Name, ObjectStatusReady, message)
 	return nil
 }
 
-func getDeploymentsForNS(clientset *kubernetes.Clientset, namespace string) (*appsv1.DeploymentList, *Error) {
+func getDeploymentsForNS(clientset *kubernetes.Clientset, namespace string, listOptions metav1.ListOptions) (*appsv1.DeploymentList, *Error) {
 	var deployments *appsv1.DeploymentList
-	deployments, err := clientset.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(context.TODO(), listOptions)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Namespace doesn't have CronJobs, return successfully
@@ -205,14 +272,18 @@ func getDeploymentsForNS(clientset *kubernetes.Clientset, namespace string) (*ap
 	return deployments, nil
 }
 
-func CloneDeployments(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) *Error {
-	deployments, err := getDeploymentsForNS(clientset, sourceNamespace)
+func CloneDeployments(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	deployments, err := getDeploymentsForNS(clientset, sourceNamespace, job.listOptions())
 	if err != nil {
 		return err
 	}
 
 	for _, deployment := range deployments.Items {
-		_, err := clientset.AppsV1().Deployments(targetNamespace).Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+		if !job.matchesName(deployment.Name) {
+			continue
+		}
+		_, err := targetClientset.AppsV1().Deployments(targetNamespace).Get(context.TODO(), deployment.Name, metav1.GetOptions{})
 		if err != nil && !errors.IsNotFound(err) {
 			// Handle unexpected errors
 			log.Printf("Error checking for existing deployment %s: %v\n", deployment.Name, err)
@@ -223,6 +294,7 @@ func CloneDeployments(clientset *kubernetes.Clientset, sourceNamespace, targetNa
 		} else if err == nil {
 			// Deployment already exists, skip creation
 			log.Printf("Deployment %s already exists in %s, skipping creation\n", deployment.Name, targetNamespace)
+			job.skip("Deployment", deployment.Name, "already exists")
 			continue
 		}
 		// Set desired image in container spec
@@ -236,60 +308,49 @@ func CloneDeployments(clientset *kubernetes.Clientset, sourceNamespace, targetNa
 		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
 		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
 		annotations[TARGET_DEPLOYMENT_ANNOTATION] = deployment.Name
-		_, err = clientset.AppsV1().Deployments(targetNamespace).Create(context.TODO(), &appsv1.Deployment{
+		mutations := applyImageOverrides(deployment.Name, "spec.template.spec", &deployment.Spec.Template.Spec, job.imageOverrides())
+		if len(mutations) > 0 {
+			// Overridden images are kept by a resync, like images patched after the clone
+			overrides := make([]string, 0, len(mutations))
+			for field := range mutations {
+				overrides = append(overrides, field)
+			}
+			annotations[TARGET_OVERRIDES_ANNOTATION] = encodeOverrides(overrides)
+		}
+		if job.zeroReplicas() {
+			deployment.Spec.Replicas = applyZeroReplicas(deployment.Spec.Replicas, annotations, mutations)
+		}
+		newDeployment := &appsv1.Deployment{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:        deployment.Name,
 				Namespace:   targetNamespace,
 				Annotations: annotations,
 			},
 			Spec: deployment.Spec,
-		}, metav1.CreateOptions{})
-
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
-			}
 		}
-		// Wait for deployment to be ready
-		for {
-			deployment, err := clientset.AppsV1().Deployments(targetNamespace).Get(context.TODO(), deployment.Name, metav1.GetOptions{})
-			if err != nil {
-				return &Error{
-					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("Error getting deployment status: %v", err),
-				}
-			}
-
-			replicas := deployment.Status.ReadyReplicas
-			if replicas == *(deployment.Spec.Replicas) {
-				log.Printf("Deployment %s is ready with %d replicas\n", deployment.Name, replicas)
-				return nil
-			}
-
-			// Deployment is not ready yet, check for errors
-			if deployment.Status.Conditions != nil {
-				for _, condition := range deployment.Status.Conditions {
-					if condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == corev1.ConditionTrue {
-						return &Error{
-							Code:    http.StatusInternalServerError,
-							Message: fmt.Sprintf("Deployment %s has failed: %s", deployment.Name, condition.Reason),
-						}
-					}
-				}
-			}
-
-			// Deployment is still in progress, wait and try again
-			time.Sleep(5 * time.Second) // Adjust the wait interval as needed
-			log.Printf("Waiting for deployment %s to be ready (%d/%d replicas)...\n", deployment.Name, replicas, *(deployment.Spec.Replicas))
+		created, errObj := job.create("Deployment", deployment.Name, annotations, mutations, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.AppsV1().Deployments(targetNamespace).Create(context.TODO(), newDeployment, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		if !created {
+			continue
+		}
+		job.record("Deployment", deployment.Name, ObjectStatusCreated, "waiting for replicas")
+		// Wait for deployment according to the job's readiness policy
+		if errObj := waitForDeployment(targetClientset, targetNamespace, deployment.Name, job); errObj != nil {
+			return errObj
 		}
 		//log.Printf("Deployment %s cloned to %s with image %s\n", deployment.Name, targetNamespace, desiredImage)
 	}
 	return nil
 }
 
-func CloneServices(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) *Error {
-	services, err := clientset.CoreV1().Services(sourceNamespace).List(context.TODO(), metav1.ListOptions{})
+func CloneServices(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	services, err := clientset.CoreV1().Services(sourceNamespace).List(context.TODO(), job.listOptions())
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Namespace doesn't have CronJobs, return successfully
@@ -297,7 +358,7 @@ func CloneServices(clientset *kubernetes.Clientset, sourceNamespace, targetNames
 			return nil
 		} else {
 			// Error checking for CronJobs
-			log.Printf("Error checking for Services:", err)
+			log.Printf("Error checking for Services: %v\n", err)
 			return &Error{
 				Code:    http.StatusInternalServerError,
 				Message: err.Error(),
@@ -306,10 +367,15 @@ func CloneServices(clientset *kubernetes.Clientset, sourceNamespace, targetNames
 	}
 
 	for _, service := range services.Items {
+		if !job.matchesName(service.Name) {
+			continue
+		}
 		// Only close the allowed types
 		if !slices.Contains(ClonedServiceTypes, service.Spec.Type) {
+			job.skip("Service", service.Name, fmt.Sprintf("service type %s is not cloned", service.Spec.Type))
 			continue
 		}
+		headless := service.Spec.ClusterIP == v1.ClusterIPNone
 		service.Spec.ClusterIP = ""           // Reset ClusterIP so that a new one is generated
 		service.Spec.ClusterIPs = []string{}  // Reset ClusterIPs so that a new one is generated
 		service.Spec.ExternalIPs = []string{} // Reset ExternalIPs so that a new one is generated
@@ -321,56 +387,65 @@ func CloneServices(clientset *kubernetes.Clientset, sourceNamespace, targetNames
 		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
 		annotations[TARGET_SERVICE_ANNOTATION] = service.Name
 
-		_, err = clientset.CoreV1().Services(targetNamespace).Create(context.TODO(), &v1.Service{
+		newService := &v1.Service{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:        service.Name,
 				Namespace:   targetNamespace,
 				Annotations: annotations,
 			},
 			Spec: service.Spec,
-		}, metav1.CreateOptions{})
-
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
-			}
 		}
-		for {
-			// Get the latest Service status
-			service, err := clientset.CoreV1().Services(targetNamespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
-			if err != nil {
-				return &Error{
-					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("Error getting Service status: %v", err),
-				}
-			}
-
-			// Check if Service has a ClusterIP assigned (assume ready when ClusterIP is available)
-			if service.Spec.ClusterIP != "" {
-				log.Printf("Service %s is ready with ClusterIP %s\n", service.Name, service.Spec.ClusterIP)
-				return nil
-			}
-
-			// Service is still being created, wait and try again
-			time.Sleep(5 * time.Second) // Adjust wait interval as needed
-			log.Printf("Waiting for Service %s to be assigned a ClusterIP...\n", service.Name)
+		mutations := map[string]interface{}{
+			"spec.clusterIP":      "",
+			"spec.clusterIPs":     []string{},
+			"spec.externalIPs":    []string{},
+			"spec.externalName":   "",
+			"spec.loadBalancerIP": "",
+		}
+		if headless {
+			// Headless services stay headless, StatefulSets need them for their pod DNS names
+			newService.Spec.ClusterIP = v1.ClusterIPNone
+			mutations["spec.clusterIP"] = v1.ClusterIPNone
+		}
+		if job.crossCluster() {
+			// Node ports are allocated per cluster, let the target cluster pick its own
+			for i := range newService.Spec.Ports {
+				newService.Spec.Ports[i].NodePort = 0
+			}
+			newService.Spec.HealthCheckNodePort = 0
+			mutations["spec.ports[].nodePort"] = 0
+			mutations["spec.healthCheckNodePort"] = 0
+		}
+		created, errObj := job.create("Service", service.Name, annotations, mutations, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.CoreV1().Services(targetNamespace).Create(context.TODO(), newService, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		if !created {
+			continue
+		}
+		job.record("Service", service.Name, ObjectStatusCreated, "waiting for ClusterIP")
+		if errObj := waitForService(targetClientset, targetNamespace, service.Name, job); errObj != nil {
+			return errObj
 		}
-		// Service exists, return success immediately (no status to check)
 	}
 	return nil
 }
 
-func CloneIstioVirtualServices(dynamicClient dynamic.Interface, sourceNamespace, targetNamespace string) *Error {
-	// Define the GVR for Istio VirtualServices
-	virtualServiceGVR := schema.GroupVersionResource{
-		Group:    "networking.istio.io",
-		Version:  "v1alpha3",
-		Resource: "virtualservices",
-	}
+// GVR for Istio VirtualServices
+var virtualServiceGVR = schema.GroupVersionResource{
+	Group:    "networking.istio.io",
+	Version:  "v1alpha3",
+	Resource: "virtualservices",
+}
+
+func CloneIstioVirtualServices(dynamicClient dynamic.Interface, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetDynamicClient := job.targetDynamicClient(dynamicClient)
 
 	// List VirtualServices in the source namespace
-	virtualServices, err := dynamicClient.Resource(virtualServiceGVR).Namespace(sourceNamespace).List(context.TODO(), metav1.ListOptions{})
+	virtualServices, err := dynamicClient.Resource(virtualServiceGVR).Namespace(sourceNamespace).List(context.TODO(), job.listOptions())
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Namespace doesn't have VirtualServices, return successfully
@@ -387,6 +462,9 @@ func CloneIstioVirtualServices(dynamicClient dynamic.Interface, sourceNamespace,
 	}
 
 	for _, item := range virtualServices.Items {
+		if !job.matchesName(item.GetName()) {
+			continue
+		}
 		// Prepare the VirtualService for cloning: clear the resource version, set the namespace, and update annotations
 		item.SetResourceVersion("")
 		item.SetNamespace(targetNamespace)
@@ -397,9 +475,14 @@ func CloneIstioVirtualServices(dynamicClient dynamic.Interface, sourceNamespace,
 		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
 		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
 		annotations[TARGET_VIRTUAL_SERVICE_ANNOTATION] = item.GetName()
+		if policy := job.virtualServicePolicy(); policy != nil {
+			// Kept so drift reports can rewrite the source routes the same way
+			value, _ := json.Marshal(policy)
+			annotations[TARGET_VIRTUAL_SERVICE_POLICY_ANNOTATION] = string(value)
+		}
 		item.SetAnnotations(annotations)
 
-		// Override all the hosts in Spec.Hosts by appending namespace name as a prefix
+		// Rewrite all the hosts in Spec.Hosts, by default by prefixing them with the target namespace
 		unstructuredSpec, exists, err := unstructured.NestedFieldNoCopy(item.Object, "spec")
 		if err != nil || !exists {
 			return &Error{
@@ -415,8 +498,13 @@ func CloneIstioVirtualServices(dynamicClient dynamic.Interface, sourceNamespace,
 				Message: fmt.Sprintf("Error accessing Hosts for VirtualService %s: %v", item.GetName(), err),
 			}
 		}
-		for i, host := range hosts {
-			hosts[i] = targetNamespace + "-" + host
+		changes := newRewriteLog("VirtualService", item.GetName())
+		hosts, err = job.hostRewrite().rewriteHosts(hosts, sourceNamespace, targetNamespace, "spec.hosts", changes)
+		if err != nil {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Error rewriting Hosts for VirtualService %s: %v", item.GetName(), err),
+			}
 		}
 		if err := unstructured.SetNestedStringSlice(spec, hosts, "hosts"); err != nil {
 			return &Error{
@@ -426,23 +514,44 @@ func CloneIstioVirtualServices(dynamicClient dynamic.Interface, sourceNamespace,
 		}
 		item.Object["spec"] = spec
 
-		// Create the VirtualService in the target namespace
-		_, err = dynamicClient.Resource(virtualServiceGVR).Namespace(targetNamespace).Create(context.TODO(), &item, metav1.CreateOptions{})
-		if err != nil {
+		// Route destinations, gateways and exportTo follow the clone's VirtualService policy
+		rewrite := istioRewrite{source: sourceNamespace, target: targetNamespace, hosts: job.hostRewrite()}
+		if err := job.virtualServicePolicy().rewrite(&item, rewrite, changes); err != nil {
 			return &Error{
 				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
+				Message: fmt.Sprintf("Error rewriting VirtualService %s: %v", item.GetName(), err),
+			}
+		}
+
+		// Create the VirtualService in the target namespace
+		newVirtualService := item.DeepCopy()
+		created, errObj := job.createResource(virtualServiceGVR, "VirtualService", item.GetName(), annotations, changes.mutations, func(opts metav1.CreateOptions) error {
+			_, err := targetDynamicClient.Resource(virtualServiceGVR).Namespace(targetNamespace).Create(context.TODO(), newVirtualService, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		job.recordRewrites(changes.rewrites)
+		if routing := job.headerRouting(); routing != nil {
+			if errObj := routeHeaderToClone(dynamicClient, sourceNamespace, targetNamespace, item.GetName(), routing, job); errObj != nil {
+				return errObj
 			}
 		}
+		if !created {
+			continue
+		}
 
 		// Log success
 		log.Printf("VirtualService %s cloned successfully to namespace %s with updated hosts\n", item.GetName(), targetNamespace)
+		job.record("VirtualService", item.GetName(), ObjectStatusReady, "")
 	}
 	return nil
 }
 
-func CloneCronJobs(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) *Error {
-	cronJobs, err := clientset.BatchV1beta1().CronJobs(sourceNamespace).List(context.TODO(), metav1.ListOptions{})
+func CloneCronJobs(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	cronJobs, err := clientset.BatchV1beta1().CronJobs(sourceNamespace).List(context.TODO(), job.listOptions())
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Namespace doesn't have CronJobs, return successfully
@@ -458,21 +567,48 @@ func CloneCronJobs(clientset *kubernetes.Clientset, sourceNamespace, targetNames
 		}
 	}
 	for _, cronJob := range cronJobs.Items {
+		if !job.matchesName(cronJob.Name) {
+			continue
+		}
+		_, err := targetClientset.BatchV1beta1().CronJobs(targetNamespace).Get(context.TODO(), cronJob.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error checking for existing CronJob %s: %v", cronJob.Name, err),
+			}
+		} else if err == nil {
+			log.Printf("CronJob %s already exists in %s, skipping creation\n", cronJob.Name, targetNamespace)
+			job.skip("CronJob", cronJob.Name, "already exists")
+			continue
+		}
 		annotations := make(map[string]string)
 		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
 		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
 		annotations[TARGET_CRONJOB_ANNOTATION] = cronJob.Name
-		cronJob.ObjectMeta.Annotations = annotations
 
-		_, err = clientset.BatchV1beta1().CronJobs(targetNamespace).Create(context.TODO(), &cronJob, metav1.CreateOptions{})
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
-			}
+		spec := *cronJob.Spec.DeepCopy()
+		mutations := applyImageOverrides(cronJob.Name, "spec.jobTemplate.spec.template.spec", &spec.JobTemplate.Spec.Template.Spec, job.imageOverrides())
+		newCronJob := &batchv1beta1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        cronJob.Name,
+				Namespace:   targetNamespace,
+				Labels:      cronJob.Labels,
+				Annotations: annotations,
+			},
+			Spec: spec,
+		}
+		created, errObj := job.create("CronJob", cronJob.Name, annotations, mutations, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.BatchV1beta1().CronJobs(targetNamespace).Create(context.TODO(), newCronJob, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		if !created {
+			continue
 		}
 		// Check if CronJob exists
-		_, err := clientset.BatchV1beta1().CronJobs(targetNamespace).Get(context.TODO(), cronJob.Name, metav1.GetOptions{})
+		_, err = targetClientset.BatchV1beta1().CronJobs(targetNamespace).Get(context.TODO(), cronJob.Name, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
 				return &Error{
@@ -489,12 +625,14 @@ func CloneCronJobs(clientset *kubernetes.Clientset, sourceNamespace, targetNames
 
 		// CronJob exists, return success immediately (no status to check)
 		log.Printf("CronJob %s is ready\n", cronJob.Name)
+		job.record("CronJob", cronJob.Name, ObjectStatusReady, "")
 	}
 	return nil
 }
 
-func CloneJobs(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) *Error {
-	jobs, err := clientset.BatchV1().Jobs(sourceNamespace).List(context.TODO(), metav1.ListOptions{})
+func CloneJobs(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	jobs, err := clientset.BatchV1().Jobs(sourceNamespace).List(context.TODO(), job.listOptions())
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Namespace doesn't have CronJobs, return successfully
@@ -509,43 +647,98 @@ func CloneJobs(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace
 			}
 		}
 	}
-	for _, job := range jobs.Items {
+	for _, srcJob := range jobs.Items {
+		if !job.matchesName(srcJob.Name) {
+			continue
+		}
+		_, err := targetClientset.BatchV1().Jobs(targetNamespace).Get(context.TODO(), srcJob.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error checking for existing Job %s: %v", srcJob.Name, err),
+			}
+		} else if err == nil {
+			log.Printf("Job %s already exists in %s, skipping creation\n", srcJob.Name, targetNamespace)
+			job.skip("Job", srcJob.Name, "already exists")
+			continue
+		}
 
 		annotations := make(map[string]string)
 		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
 		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
-		annotations[TARGET_JOB_ANNOTATION] = job.Name
-		job.ObjectMeta.Annotations = annotations
+		annotations[TARGET_JOB_ANNOTATION] = srcJob.Name
 
-		_, err = clientset.BatchV1().Jobs(targetNamespace).Create(context.TODO(), &job, metav1.CreateOptions{})
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
-			}
+		spec := *srcJob.Spec.DeepCopy()
+		// The generated selector and labels point at the source Job's uid, the API server generates new ones
+		if spec.ManualSelector == nil || !*spec.ManualSelector {
+			spec.Selector = nil
+		}
+		spec.Template.Labels = withoutJobControllerLabels(spec.Template.Labels)
+		mutations := applyImageOverrides(srcJob.Name, "spec.template.spec", &spec.Template.Spec, job.imageOverrides())
+		newJob := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        srcJob.Name,
+				Namespace:   targetNamespace,
+				Labels:      withoutJobControllerLabels(srcJob.Labels),
+				Annotations: annotations,
+			},
+			Spec: spec,
+		}
+		created, errObj := job.create("Job", srcJob.Name, annotations, mutations, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.BatchV1().Jobs(targetNamespace).Create(context.TODO(), newJob, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		if !created {
+			continue
 		}
 		// Check if Job exists
-		_, err := clientset.BatchV1().Jobs(targetNamespace).Get(context.TODO(), job.Name, metav1.GetOptions{})
+		_, err = targetClientset.BatchV1().Jobs(targetNamespace).Get(context.TODO(), srcJob.Name, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
 				return &Error{
 					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("Job %s not found in namespace %s", job.Name, targetNamespace),
+					Message: fmt.Sprintf("Job %s not found in namespace %s", srcJob.Name, targetNamespace),
 				}
 			} else {
 				return &Error{
 					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("Error checking for Job %s: %v", job.Name, err),
+					Message: fmt.Sprintf("Error checking for Job %s: %v", srcJob.Name, err),
 				}
 			}
 		}
 
 		// Job exists, return success immediately (no status to check)
-		log.Printf("Job %s is ready\n", job.Name)
+		log.Printf("Job %s is ready\n", srcJob.Name)
+		job.record("Job", srcJob.Name, ObjectStatusReady, "")
 	}
 	return nil
 }
 
+// Labels the Job controller sets from the uid and name of a Job
+var jobControllerLabels = []string{
+	"controller-uid",
+	"job-name",
+	batchv1.ControllerUidLabel,
+	batchv1.JobNameLabel,
+}
+
+// withoutJobControllerLabels copies labels without the ones set by the Job controller
+func withoutJobControllerLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	cleaned := make(map[string]string, len(labels))
+	for key, value := range labels {
+		if !slices.Contains(jobControllerLabels, key) {
+			cleaned[key] = value
+		}
+	}
+	return cleaned
+}
+
 // Helper functions for error handling
 func hasStatefulSetUpdateFailure(statefulSet *appsv1.StatefulSet) bool {
 	// Implement logic to check for specific failure conditions in StatefulSet status
@@ -568,8 +761,9 @@ func getStatefulSetFailureReason(statefulSet *appsv1.StatefulSet) string {
 }
 
 // TODO: Need to check this
-func CloneSTS(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) *Error {
-	statefulSets, err := clientset.AppsV1().StatefulSets(sourceNamespace).List(context.TODO(), metav1.ListOptions{})
+func CloneSTS(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	statefulSets, err := clientset.AppsV1().StatefulSets(sourceNamespace).List(context.TODO(), job.listOptions())
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Namespace doesn't have CronJobs, return successfully
@@ -584,89 +778,98 @@ func CloneSTS(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace
 			}
 		}
 	}
+	overrides := job.statefulSetOverrides()
 	for _, statefulSet := range statefulSets.Items {
-		_, err = clientset.AppsV1().StatefulSets(targetNamespace).Create(context.TODO(), &statefulSet, metav1.CreateOptions{})
-		if err != nil {
+		if !job.matchesName(statefulSet.Name) {
+			continue
+		}
+		_, err := targetClientset.AppsV1().StatefulSets(targetNamespace).Get(context.TODO(), statefulSet.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			log.Printf("Error checking for existing StatefulSet %s: %v\n", statefulSet.Name, err)
 			return &Error{
 				Code:    http.StatusInternalServerError,
 				Message: err.Error(),
 			}
-		}
-		// Check if StatefulSet exists
-		_, err := clientset.AppsV1().StatefulSets(targetNamespace).Get(context.TODO(), statefulSet.Name, metav1.GetOptions{})
-		if err != nil {
-			if errors.IsNotFound(err) {
-				return &Error{
-					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("StatefulSet %s not found in namespace %s", statefulSet.Name, targetNamespace),
-				}
-			} else {
-				return &Error{
-					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("Error checking for StatefulSet %s: %v", statefulSet.Name, err),
-				}
-			}
+		} else if err == nil {
+			log.Printf("StatefulSet %s already exists in %s, skipping creation\n", statefulSet.Name, targetNamespace)
+			job.skip("StatefulSet", statefulSet.Name, "already exists")
+			continue
 		}
 
-		// Check if StatefulSet exists
-		statefulSet, err := clientset.AppsV1().StatefulSets(targetNamespace).Get(context.TODO(), statefulSet.Name, metav1.GetOptions{})
-		if err != nil {
-			if errors.IsNotFound(err) {
-				return &Error{
-					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("StatefulSet %s not found in namespace %s", statefulSet.Name, targetNamespace),
-				}
-			} else {
-				return &Error{
-					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("Error checking for StatefulSet %s: %v", statefulSet.Name, err),
-				}
-			}
+		// Pods of a StatefulSet get their DNS names from the governing headless service, cloned before. Without
+		// it the StatefulSet is skipped, the rest of the clone goes on.
+		reason, errObj := validateServiceName(clientset, targetClientset, sourceNamespace, targetNamespace, &statefulSet, job)
+		if errObj != nil {
+			return errObj
+		}
+		if reason != "" {
+			log.Printf("Skipping StatefulSet %s: %s\n", statefulSet.Name, reason)
+			job.skip("StatefulSet", statefulSet.Name, reason)
+			continue
 		}
 
-		// Wait for StatefulSet to be ready
-		for {
-			// Get the latest StatefulSet status
-			statefulSet, err = clientset.AppsV1().StatefulSets(targetNamespace).Get(context.TODO(), statefulSet.Name, metav1.GetOptions{})
-			if err != nil {
-				return &Error{
-					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("Error getting StatefulSet status: %v", err),
-				}
-			}
-
-			// Check if all replicas are ready
-			if statefulSet.Status.ReadyReplicas == *(statefulSet.Spec.Replicas) {
-				log.Printf("StatefulSet %s is ready with %d replicas\n", statefulSet.Name, statefulSet.Status.ReadyReplicas)
-				return nil
-			}
-
-			// Check for errors
-			if hasStatefulSetUpdateFailure(statefulSet) {
-				return &Error{
-					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("StatefulSet %s has failed: %s", statefulSet.Name, getStatefulSetFailureReason(statefulSet)),
-				}
-
-			}
-
-			// StatefulSet is still rolling out, wait and try again
-			time.Sleep(5 * time.Second) // Adjust the wait interval as needed
-			log.Printf("Waiting for StatefulSet %s to be ready (%d/%d replicas)...\n", statefulSet.Name, statefulSet.Status.ReadyReplicas, *(statefulSet.Spec.Replicas))
+		annotations := make(map[string]string)
+		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+		annotations[TARGET_STS_ANNOTATION] = statefulSet.Name
+		mutations := applyImageOverrides(statefulSet.Name, "spec.template.spec", &statefulSet.Spec.Template.Spec, job.imageOverrides())
+		if len(mutations) > 0 {
+			// Overridden images are kept by a resync, like images patched after the clone
+			fields := make([]string, 0, len(mutations))
+			for field := range mutations {
+				fields = append(fields, field)
+			}
+			annotations[TARGET_OVERRIDES_ANNOTATION] = encodeOverrides(fields)
+		}
+		spec := *statefulSet.Spec.DeepCopy()
+		spec.VolumeClaimTemplates = cleanClaimTemplates(statefulSet.Spec.VolumeClaimTemplates, overrides, mutations)
+		if overrides != nil && overrides.Replicas != nil {
+			replicas := *overrides.Replicas
+			spec.Replicas = &replicas
+			annotations[TARGET_OVERRIDES_ANNOTATION] = encodeOverrides(append(getOverrides(annotations), replicasOverride))
+			mutations[replicasOverride] = replicas
+		}
+		if job.zeroReplicas() {
+			spec.Replicas = applyZeroReplicas(spec.Replicas, annotations, mutations)
+		}
+		newStatefulSet := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        statefulSet.Name,
+				Namespace:   targetNamespace,
+				Labels:      statefulSet.Labels,
+				Annotations: annotations,
+			},
+			Spec: spec,
+		}
+		created, errObj := job.create("StatefulSet", statefulSet.Name, annotations, mutations, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.AppsV1().StatefulSets(targetNamespace).Create(context.TODO(), newStatefulSet, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		if !created {
+			continue
+		}
+		job.record("StatefulSet", statefulSet.Name, ObjectStatusCreated, "waiting for replicas")
+		// Wait for StatefulSet according to the job's readiness policy
+		if errObj := waitForStatefulSet(targetClientset, targetNamespace, statefulSet.Name, job); errObj != nil {
+			return errObj
 		}
 	}
 	return nil
 }
 
-func CloneIngresses(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) *Error {
-	ingresses, err := clientset.ExtensionsV1beta1().Ingresses(sourceNamespace).List(context.TODO(), metav1.ListOptions{})
+func CloneIngresses(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	ingresses, err := clientset.NetworkingV1().Ingresses(sourceNamespace).List(context.TODO(), job.listOptions())
 	if err != nil {
 		if errors.IsNotFound(err) {
-			// Namespace doesn't have CronJobs, return successfully
+			// Namespace doesn't have Ingresses, return successfully
 			log.Printf("Namespace %s does not have any Ingress\n", sourceNamespace)
 			return nil
 		} else {
-			// Error checking for CronJobs
+			// Error checking for Ingresses
 			fmt.Println("Error checking for Ingress:", err)
 			return &Error{
 				Code:    http.StatusInternalServerError,
@@ -674,21 +877,71 @@ func CloneIngresses(clientset *kubernetes.Clientset, sourceNamespace, targetName
 			}
 		}
 	}
+	hostRewrite := job.hostRewrite()
 	for _, ingress := range ingresses.Items {
+		if !job.matchesName(ingress.Name) {
+			continue
+		}
 		annotations := make(map[string]string)
 		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
 		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
 		annotations[TARGET_INGRESS_ANNOTATION] = ingress.Name
-		ingress.ObjectMeta.Annotations = annotations
-		_, err = clientset.ExtensionsV1beta1().Ingresses(targetNamespace).Create(context.TODO(), &ingress, metav1.CreateOptions{})
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
+		newIngress := &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        ingress.Name,
+				Namespace:   targetNamespace,
+				Labels:      ingress.Labels,
+				Annotations: annotations,
+			},
+			Spec: *ingress.Spec.DeepCopy(),
+		}
+
+		// Rewrite the hosts so the clone doesn't claim the hostnames of the source
+		changes := newRewriteLog("Ingress", ingress.Name)
+		for i, rule := range newIngress.Spec.Rules {
+			host, err := hostRewrite.rewriteHost(rule.Host, sourceNamespace, targetNamespace)
+			if err != nil {
+				return &Error{
+					Code:    http.StatusBadRequest,
+					Message: fmt.Sprintf("Error rewriting hosts of Ingress %s: %v", ingress.Name, err),
+				}
 			}
+			if host != rule.Host {
+				newIngress.Spec.Rules[i].Host = host
+				changes.record(fmt.Sprintf("spec.rules[%d].host", i), rule.Host, host)
+			}
+		}
+		for i, tls := range newIngress.Spec.TLS {
+			hosts, err := hostRewrite.rewriteHosts(tls.Hosts, sourceNamespace, targetNamespace, fmt.Sprintf("spec.tls[%d].hosts", i), changes)
+			if err != nil {
+				return &Error{
+					Code:    http.StatusBadRequest,
+					Message: fmt.Sprintf("Error rewriting TLS hosts of Ingress %s: %v", ingress.Name, err),
+				}
+			}
+			newIngress.Spec.TLS[i].Hosts = hosts
+		}
+		if hostRewrite != nil && hostRewrite.CopyTLSSecrets {
+			for _, tls := range newIngress.Spec.TLS {
+				if errObj := copyTLSSecret(clientset, targetClientset, sourceNamespace, targetNamespace, tls.SecretName, job); errObj != nil {
+					return errObj
+				}
+			}
+		}
+
+		created, errObj := job.create("Ingress", ingress.Name, annotations, changes.mutations, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.NetworkingV1().Ingresses(targetNamespace).Create(context.TODO(), newIngress, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		job.recordRewrites(changes.rewrites)
+		if !created {
+			continue
 		}
 		// Check if Ingress exists
-		_, err := clientset.ExtensionsV1beta1().Ingresses(targetNamespace).Get(context.TODO(), ingress.Name, metav1.GetOptions{})
+		_, err := targetClientset.NetworkingV1().Ingresses(targetNamespace).Get(context.TODO(), ingress.Name, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
 				return &Error{
@@ -705,12 +958,67 @@ func CloneIngresses(clientset *kubernetes.Clientset, sourceNamespace, targetName
 
 		// Ingress exists, return success immediately (no status to check)
 		log.Printf("Ingress %s is ready\n", ingress.Name)
+		job.record("Ingress", ingress.Name, ObjectStatusReady, "")
+	}
+	return nil
+}
+
+// copyTLSSecret copies a TLS secret referenced by an Ingress when the secret step didn't clone it,
+// e.g. because it was excluded or filtered out
+func copyTLSSecret(clientset, targetClientset *kubernetes.Clientset, sourceNamespace, targetNamespace, name string, job *CloneJob) *Error {
+	if name == "" {
+		return nil
+	}
+	_, err := targetClientset.CoreV1().Secrets(targetNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error checking for TLS secret %s: %v", name, err),
+		}
 	}
+	secret, err := clientset.CoreV1().Secrets(sourceNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Printf("TLS secret %s not found in namespace %s, skipping copy\n", name, sourceNamespace)
+			job.skip("Secret", name, "TLS secret not found in source namespace")
+			return nil
+		}
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error reading TLS secret %s: %v", name, err),
+		}
+	}
+	annotations := make(map[string]string)
+	annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+	annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+	annotations[TARGET_SECRET_ANNOTATION] = secret.Name
+	newSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secret.Name,
+			Namespace:   targetNamespace,
+			Annotations: annotations,
+		},
+		Type: secret.Type,
+		Data: secret.Data,
+	}
+	created, errObj := job.create("Secret", secret.Name, annotations, nil, func(opts metav1.CreateOptions) error {
+		_, err := targetClientset.CoreV1().Secrets(targetNamespace).Create(context.TODO(), newSecret, opts)
+		return err
+	})
+	if errObj != nil || !created {
+		return errObj
+	}
+	log.Printf("Copied TLS secret %s to namespace %s\n", secret.Name, targetNamespace)
+	job.record("Secret", secret.Name, ObjectStatusReady, "copied for Ingress TLS")
 	return nil
 }
 
-func CloneSeviceAccount(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) *Error {
-	serviceAccounts, err := clientset.CoreV1().ServiceAccounts(sourceNamespace).List(context.TODO(), metav1.ListOptions{})
+func CloneSeviceAccount(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	serviceAccounts, err := clientset.CoreV1().ServiceAccounts(sourceNamespace).List(context.TODO(), job.listOptions())
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Namespace doesn't have ServiceAccounts, return successfully
@@ -725,8 +1033,20 @@ func CloneSeviceAccount(clientset *kubernetes.Clientset, sourceNamespace, target
 			}
 		}
 	}
+	tokens, err := clientset.CoreV1().Secrets(sourceNamespace).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("type", string(v1.SecretTypeServiceAccountToken)).String(),
+	})
+	if err != nil {
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error listing service account tokens in %s: %v", sourceNamespace, err),
+		}
+	}
 	for _, serviceAccount := range serviceAccounts.Items {
-		_, err := clientset.CoreV1().ServiceAccounts(targetNamespace).Get(context.TODO(), serviceAccount.Name, metav1.GetOptions{})
+		if !job.matchesName(serviceAccount.Name) {
+			continue
+		}
+		_, err := targetClientset.CoreV1().ServiceAccounts(targetNamespace).Get(context.TODO(), serviceAccount.Name, metav1.GetOptions{})
 		if err != nil && !errors.IsNotFound(err) {
 			// Handle unexpected errors
 			log.Printf("Error checking for existing serviceAccount %s: %v\n", serviceAccount.Name, err)
@@ -737,8 +1057,12 @@ func CloneSeviceAccount(clientset *kubernetes.Clientset, sourceNamespace, target
 		} else if err == nil {
 			// ServiceAccount already exists, skip creation
 			log.Printf("ServiceAccount %s already exists in %s, skipping creation\n", serviceAccount.Name, targetNamespace)
+			job.skip("ServiceAccount", serviceAccount.Name, "already exists")
 			continue
 		}
+		if errObj := clonePullSecrets(clientset, targetClientset, &serviceAccount, sourceNamespace, targetNamespace, job); errObj != nil {
+			return errObj
+		}
 		annotations := make(map[string]string)
 		for key, value := range serviceAccount.Annotations {
 			annotations[key] = value
@@ -747,27 +1071,44 @@ func CloneSeviceAccount(clientset *kubernetes.Clientset, sourceNamespace, target
 		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
 		annotations[TARGET_SA_ANNOTATION] = serviceAccount.Name
 
-		_, err = clientset.CoreV1().ServiceAccounts(targetNamespace).Create(context.TODO(), &v1.ServiceAccount{
+		newServiceAccount := &v1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:        serviceAccount.Name,
 				Namespace:   targetNamespace,
+				Labels:      serviceAccount.Labels,
 				Annotations: annotations,
 			},
-		}, metav1.CreateOptions{})
-
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
+			ImagePullSecrets: serviceAccount.ImagePullSecrets,
+			// Token secrets keep their names when they are regenerated
+			Secrets:                      serviceAccount.Secrets,
+			AutomountServiceAccountToken: serviceAccount.AutomountServiceAccountToken,
+		}
+		created, errObj := job.create("ServiceAccount", serviceAccount.Name, annotations, nil, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.CoreV1().ServiceAccounts(targetNamespace).Create(context.TODO(), newServiceAccount, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		// The token controller fills in regenerated tokens once their ServiceAccount exists
+		for i := range tokens.Items {
+			if tokens.Items[i].Annotations[v1.ServiceAccountNameKey] != serviceAccount.Name {
+				continue
 			}
+			if errObj := regenerateToken(targetClientset, &tokens.Items[i], sourceNamespace, targetNamespace, job); errObj != nil {
+				return errObj
+			}
+		}
+		if !created {
+			continue
 		}
 		// Check if ServiceAccount exists
-		_, err = clientset.CoreV1().ServiceAccounts(targetNamespace).Get(context.TODO(), serviceAccount.Name, metav1.GetOptions{})
+		_, err = targetClientset.CoreV1().ServiceAccounts(targetNamespace).Get(context.TODO(), serviceAccount.Name, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
 				return &Error{
 					Code:    http.StatusBadRequest,
-					Message: fmt.Sprintf("", serviceAccount.Name, targetNamespace),
+					Message: fmt.Sprintf("ServiceAccount %s not found in namespace %s", serviceAccount.Name, targetNamespace),
 				}
 			} else {
 				return &Error{
@@ -777,12 +1118,14 @@ func CloneSeviceAccount(clientset *kubernetes.Clientset, sourceNamespace, target
 			}
 
 		}
+		job.record("ServiceAccount", serviceAccount.Name, ObjectStatusReady, "")
 	}
 	return nil
 }
 
-func ClonePDB(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) *Error {
-	podDisruptionBudgets, err := clientset.PolicyV1beta1().PodDisruptionBudgets(sourceNamespace).List(context.TODO(), metav1.ListOptions{})
+func ClonePDB(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	podDisruptionBudgets, err := clientset.PolicyV1beta1().PodDisruptionBudgets(sourceNamespace).List(context.TODO(), job.listOptions())
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Namespace doesn't have CronJobs, return successfully
@@ -798,15 +1141,45 @@ func ClonePDB(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace
 		}
 	}
 	for _, podDisruptionBudget := range podDisruptionBudgets.Items {
-		_, err = clientset.PolicyV1beta1().PodDisruptionBudgets(targetNamespace).Create(context.TODO(), &podDisruptionBudget, metav1.CreateOptions{})
-		if err != nil {
+		if !job.matchesName(podDisruptionBudget.Name) {
+			continue
+		}
+		_, err := targetClientset.PolicyV1beta1().PodDisruptionBudgets(targetNamespace).Get(context.TODO(), podDisruptionBudget.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
 			return &Error{
 				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
+				Message: fmt.Sprintf("Error checking for existing PodDisruptionBudget %s: %v", podDisruptionBudget.Name, err),
 			}
+		} else if err == nil {
+			log.Printf("PodDisruptionBudget %s already exists in %s, skipping creation\n", podDisruptionBudget.Name, targetNamespace)
+			job.skip("PodDisruptionBudget", podDisruptionBudget.Name, "already exists")
+			continue
+		}
+		annotations := make(map[string]string)
+		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+		annotations[TARGET_PDB_ANNOTATION] = podDisruptionBudget.Name
+		newPodDisruptionBudget := &policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        podDisruptionBudget.Name,
+				Namespace:   targetNamespace,
+				Labels:      podDisruptionBudget.Labels,
+				Annotations: annotations,
+			},
+			Spec: podDisruptionBudget.Spec,
+		}
+		created, errObj := job.create("PodDisruptionBudget", podDisruptionBudget.Name, annotations, nil, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.PolicyV1beta1().PodDisruptionBudgets(targetNamespace).Create(context.TODO(), newPodDisruptionBudget, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		if !created {
+			continue
 		}
 		// Check if PDB exists
-		_, err := clientset.PolicyV1beta1().PodDisruptionBudgets(targetNamespace).Get(context.TODO(), podDisruptionBudget.Name, metav1.GetOptions{})
+		_, err = targetClientset.PolicyV1beta1().PodDisruptionBudgets(targetNamespace).Get(context.TODO(), podDisruptionBudget.Name, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
 				return &Error{
@@ -823,12 +1196,14 @@ func ClonePDB(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace
 
 		// PDB exists, return success immediately (no status to check)
 		log.Printf("PodDisruptionBudget %s is ready\n", podDisruptionBudget.Name)
+		job.record("PodDisruptionBudget", podDisruptionBudget.Name, ObjectStatusReady, "")
 	}
 	return nil
 }
 
-func CloneHPA(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) *Error {
-	horizontalPodAutoscalers, err := clientset.AutoscalingV1().HorizontalPodAutoscalers(sourceNamespace).List(context.TODO(), metav1.ListOptions{})
+func CloneHPA(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	horizontalPodAutoscalers, err := clientset.AutoscalingV1().HorizontalPodAutoscalers(sourceNamespace).List(context.TODO(), job.listOptions())
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Namespace doesn't have CronJobs, return successfully
@@ -844,15 +1219,45 @@ func CloneHPA(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace
 		}
 	}
 	for _, horizontalPodAutoscaler := range horizontalPodAutoscalers.Items {
-		_, err = clientset.AutoscalingV1().HorizontalPodAutoscalers(targetNamespace).Create(context.TODO(), &horizontalPodAutoscaler, metav1.CreateOptions{})
-		if err != nil {
+		if !job.matchesName(horizontalPodAutoscaler.Name) {
+			continue
+		}
+		_, err := targetClientset.AutoscalingV1().HorizontalPodAutoscalers(targetNamespace).Get(context.TODO(), horizontalPodAutoscaler.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
 			return &Error{
 				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
+				Message: fmt.Sprintf("Error checking for existing HorizontalPodAutoscaler %s: %v", horizontalPodAutoscaler.Name, err),
 			}
+		} else if err == nil {
+			log.Printf("HorizontalPodAutoscaler %s already exists in %s, skipping creation\n", horizontalPodAutoscaler.Name, targetNamespace)
+			job.skip("HorizontalPodAutoscaler", horizontalPodAutoscaler.Name, "already exists")
+			continue
+		}
+		annotations := make(map[string]string)
+		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+		annotations[TARGET_HPA_ANNOTATION] = horizontalPodAutoscaler.Name
+		newHorizontalPodAutoscaler := &autoscalingv1.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        horizontalPodAutoscaler.Name,
+				Namespace:   targetNamespace,
+				Labels:      horizontalPodAutoscaler.Labels,
+				Annotations: annotations,
+			},
+			Spec: horizontalPodAutoscaler.Spec,
+		}
+		created, errObj := job.create("HorizontalPodAutoscaler", horizontalPodAutoscaler.Name, annotations, nil, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.AutoscalingV1().HorizontalPodAutoscalers(targetNamespace).Create(context.TODO(), newHorizontalPodAutoscaler, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		if !created {
+			continue
 		}
 		// Check if HPA exists
-		_, err := clientset.AutoscalingV1().HorizontalPodAutoscalers(targetNamespace).Get(context.TODO(), horizontalPodAutoscaler.Name, metav1.GetOptions{})
+		_, err = targetClientset.AutoscalingV1().HorizontalPodAutoscalers(targetNamespace).Get(context.TODO(), horizontalPodAutoscaler.Name, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
 				return &Error{
@@ -869,13 +1274,26 @@ func CloneHPA(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace
 
 		// HPA exists, return success immediately (no status to check)
 		log.Printf("HorizontalPodAutoscaler %s is ready\n", horizontalPodAutoscaler.Name)
+		job.record("HorizontalPodAutoscaler", horizontalPodAutoscaler.Name, ObjectStatusReady, "")
 	}
 	return nil
 }
 
-func RemoveNamespace(clientset *kubernetes.Clientset, namespace string) *Error {
+func RemoveNamespace(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, namespace string) *Error {
+	return removeNamespace(clientset, dynamicClient, namespace, nil)
+}
+
+// Cleanups run before a namespace is deleted, undoing the changes a clone made outside of its namespace
+var namespaceDeletionHooks = []func(*kubernetes.Clientset, dynamic.Interface, *v1.Namespace) *Error{
+	removeCloneTraffic,
+	removeVolumeSources,
+}
+
+// removeNamespace deletes a namespace and waits until it is gone, progress is called with the
+// namespace every time it changes while it terminates
+func removeNamespace(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, namespace string, progress func(*v1.Namespace)) *Error {
 	// Check if namespace exists
-	_, err := clientset.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	ns, err := clientset.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			log.Printf("Namespace %s does not exist, nothing to delete\n", namespace)
@@ -889,6 +1307,12 @@ func RemoveNamespace(clientset *kubernetes.Clientset, namespace string) *Error {
 		}
 	}
 
+	for _, hook := range namespaceDeletionHooks {
+		if errObj := hook(clientset, dynamicClient, ns); errObj != nil {
+			return errObj
+		}
+	}
+
 	// Delete the namespace
 	err = clientset.CoreV1().Namespaces().Delete(context.TODO(), namespace, metav1.DeleteOptions{})
 	if err != nil {
@@ -899,227 +1323,172 @@ func RemoveNamespace(clientset *kubernetes.Clientset, namespace string) *Error {
 	}
 
 	// Wait for namespace deletion to complete
-	for {
-		_, err := clientset.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
-		if err != nil {
-			if errors.IsNotFound(err) {
-				log.Printf("Namespace %s deleted successfully\n", namespace)
-				return nil
-			} else {
-				return &Error{
-					Code:    http.StatusInternalServerError,
-					Message: fmt.Sprintf("Error checking for namespace deletion: %v", err),
-				}
-			}
-		}
-
-		// Namespace still exists, wait and try again
-		log.Printf("Waiting for namespace %s to be deleted...\n", namespace)
-		time.Sleep(5 * time.Second) // Adjust the wait interval as needed
+	if errObj := waitForNamespaceDeletion(context.TODO(), clientset, namespace, DefaultReadinessTimeouts["Namespace"], progress); errObj != nil {
+		return errObj
 	}
+	log.Printf("Namespace %s deleted successfully\n", namespace)
+	return nil
+}
+
+const genericStepKind = "Generic"
+
+// cloneStep clones all objects of one kind as part of CloneNamespace
+type cloneStep struct {
+	kind  string
+	clone func() *Error
 }
 
-func CloneNamespace(clientset *kubernetes.Clientset, dynamicClientSet *dynamic.DynamicClient, sourceNamespace, targetNamespace string) *Error {
+func CloneNamespace(clientset *kubernetes.Clientset, dynamicClientSet *dynamic.DynamicClient, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
 	// Create the target namespace if it doesn't exist
 	annotations := make(map[string]string)
 	annotations[TARGET_NS_ANNOTATION] = sourceNamespace
 	annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+	if expiresAt := job.expiry(); expiresAt != nil {
+		annotations[TARGET_EXPIRES_AT_ANNOTATION] = expiresAt.Format(time.RFC3339)
+	}
+	if job != nil {
+		options, _ := json.Marshal(job.Options.persisted())
+		annotations[TARGET_CLONE_OPTIONS_ANNOTATION] = string(options)
+	}
+
+	// Namespace level objects are created in the target cluster, which is the source cluster unless the
+	// job has a target cluster
+	targetClientset := job.targetClientset(clientset)
+	targetDynamicClient := job.targetDynamicClient(dynamicClientSet)
 
-	_, err := clientset.CoreV1().Namespaces().Create(context.TODO(), &v1.Namespace{
+	job.setCurrentKind("Namespace")
+	// Set when this job created the target namespace, only then a failed clone removes it
+	createdNamespace := false
+	newNamespace := &v1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        targetNamespace,
 			Annotations: annotations,
 		},
-	}, metav1.CreateOptions{})
-
-	if err != nil && !strings.Contains(err.Error(), "AlreadyExists") {
-		errStr := fmt.Sprintf("Error creating namespace %s: %v\n", targetNamespace, err)
-		log.Printf(errStr)
-		return &Error{
-			Code:    http.StatusInternalServerError,
-			Message: errStr,
-		}
-	}
-
-	// Apply Kube Green Annotations to the entire namespace
-	errObj := applyKubeGreen(clientset, dynamicClientSet, targetNamespace)
-	if errObj != nil {
-		// Remove the Target Namespace
-		// TODO: Probably move the namespace deletion to a go routine for returning faster?
-		errObj = RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
-			}
-		}
-		return errObj
-	}
-
-	errObj = CloneConfigMap(clientset, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		// Remove the Target Namespace
-		// TODO: Probably move the namespace deletion to a go routine for returning faster?
-		errObj = RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
-			}
-		}
-		return errObj
-	}
-
-	errObj = CloneSeviceAccount(clientset, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		// Remove the Target Namespace
-		err := RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
-			}
-		}
-		return errObj
 	}
-
-	errObj = CloneSecret(clientset, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		// Remove the Target Namespace
-		err := RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
+	if job.dryRun() {
+		_, err := targetClientset.CoreV1().Namespaces().Get(context.TODO(), targetNamespace, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
 			return &Error{
 				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
+				Message: fmt.Sprintf("Error checking for namespace %s: %v", targetNamespace, err),
 			}
 		}
-		return &Error{
-			Code:    http.StatusInternalServerError,
-			Message: fmt.Sprintf("Error cloning Secrets: %v\n", err),
-		}
-	}
-
-	errObj = CloneDeployments(clientset, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		// Remove the Target Namespace
-		err := RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
+		if err == nil {
+			job.skip("Namespace", targetNamespace, "already exists")
+		} else {
+			job.markTargetMissing()
+			_, errObj := job.create("Namespace", targetNamespace, annotations, nil, func(opts metav1.CreateOptions) error {
+				_, err := targetClientset.CoreV1().Namespaces().Create(context.TODO(), newNamespace, opts)
+				return err
+			})
+			if errObj != nil {
+				return errObj
 			}
 		}
-		return err
-	}
+	} else {
+		_, err := targetClientset.CoreV1().Namespaces().Create(context.TODO(), newNamespace, metav1.CreateOptions{})
 
-	errObj = CloneServices(clientset, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		log.Printf("Error cloning Services: %v\n", errObj)
-		// Remove the Target Namespace
-		err := RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
+		if err != nil && !errors.IsAlreadyExists(err) {
+			errStr := fmt.Sprintf("Error creating namespace %s: %v\n", targetNamespace, err)
+			log.Printf(errStr)
+			job.record("Namespace", targetNamespace, ObjectStatusFailed, err.Error())
 			return &Error{
 				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
+				Message: errStr,
 			}
 		}
-		return err
-	}
-
-	errObj = CloneIstioVirtualServices(dynamicClientSet, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		log.Printf("Error cloning Istio VirtualServices: %v\n", errObj)
-		// Remove the Target Namespace
-		err := RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
-			}
+		if err == nil {
+			createdNamespace = true
+			job.record("Namespace", targetNamespace, ObjectStatusReady, "")
+		} else {
+			job.skip("Namespace", targetNamespace, "already exists")
 		}
-		return err
 	}
 
-	errObj = CloneCronJobs(clientset, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		log.Printf("Error cloning CronJobs: %v\n", err)
-		// Remove the Target Namespace
-		err := RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
-			}
-		}
-		return err
+	// Objects are cloned in order, a failure in any step rolls back what the job created
+	steps := []cloneStep{
+		// Apply Kube Green Annotations to the entire namespace
+		{KUBE_GREEN_KIND, func() *Error { return applyKubeGreen(targetClientset, targetDynamicClient, targetNamespace, job) }},
+		// Quotas go before anything that could exceed them
+		{"ResourceQuota", func() *Error { return CloneResourceQuotas(clientset, sourceNamespace, targetNamespace, job) }},
+		{"LimitRange", func() *Error { return CloneLimitRanges(clientset, sourceNamespace, targetNamespace, job) }},
+		{"ConfigMap", func() *Error { return CloneConfigMap(clientset, sourceNamespace, targetNamespace, job) }},
+		// Pull secrets exist before the ServiceAccounts referencing them
+		{"Secret", func() *Error { return CloneSecret(clientset, sourceNamespace, targetNamespace, job) }},
+		{"ServiceAccount", func() *Error { return CloneSeviceAccount(clientset, sourceNamespace, targetNamespace, job) }},
+		{"Role", func() *Error { return CloneRoles(clientset, sourceNamespace, targetNamespace, job) }},
+		{"RoleBinding", func() *Error { return CloneRoleBindings(clientset, sourceNamespace, targetNamespace, job) }},
+		{"ClusterRoleBinding", func() *Error {
+			return CloneClusterRoleBindings(clientset, sourceNamespace, targetNamespace, job)
+		}},
+		// Policies go first so workloads never start without them
+		{"NetworkPolicy", func() *Error { return CloneNetworkPolicies(clientset, sourceNamespace, targetNamespace, job) }},
+		// Claims exist before the workloads mounting them
+		{"PersistentVolumeClaim", func() *Error {
+			return CloneVolumeClaims(clientset, dynamicClientSet, sourceNamespace, targetNamespace, job)
+		}},
+		{"Deployment", func() *Error { return CloneDeployments(clientset, sourceNamespace, targetNamespace, job) }},
+		{"Service", func() *Error { return CloneServices(clientset, sourceNamespace, targetNamespace, job) }},
+		{"VirtualService", func() *Error {
+			return CloneIstioVirtualServices(dynamicClientSet, sourceNamespace, targetNamespace, job)
+		}},
+		{"CronJob", func() *Error { return CloneCronJobs(clientset, sourceNamespace, targetNamespace, job) }},
+		{"Job", func() *Error { return CloneJobs(clientset, sourceNamespace, targetNamespace, job) }},
+		{"StatefulSet", func() *Error { return CloneSTS(clientset, sourceNamespace, targetNamespace, job) }},
+		{"Ingress", func() *Error { return CloneIngresses(clientset, sourceNamespace, targetNamespace, job) }},
+		{"PodDisruptionBudget", func() *Error { return ClonePDB(clientset, sourceNamespace, targetNamespace, job) }},
+		{"HorizontalPodAutoscaler", func() *Error { return CloneHPA(clientset, sourceNamespace, targetNamespace, job) }},
 	}
-
-	errObj = CloneJobs(clientset, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		log.Printf("Error cloning Jobs: %v\n", err)
-		// Remove the Target Namespace
-		err := RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
-			}
-		}
-		return err
+	for _, resource := range istioResources {
+		resource := resource
+		steps = append(steps, cloneStep{resource.kind, func() *Error {
+			return cloneIstioResource(dynamicClientSet, resource, sourceNamespace, targetNamespace, job)
+		}})
 	}
-
-	errObj = CloneSTS(clientset, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		log.Printf("Error cloning STS: %v\n", err)
-		// Remove the Target Namespace
-		err := RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
-			}
-		}
-		return err
+	if job != nil && job.Options.CloneAllResources {
+		steps = append(steps, cloneStep{genericStepKind, func() *Error {
+			return CloneGenericResources(clientset, dynamicClientSet, sourceNamespace, targetNamespace, job)
+		}})
 	}
 
-	errObj = CloneIngresses(clientset, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		log.Printf("Error cloning Ingress: %v\n", err)
-		// Remove the Target Namespace
-		err := RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
-			}
+	for _, step := range steps {
+		// Kind filters only apply to objects copied from the source. SleepInfo is generated for the clone
+		// and the generic step filters each discovered kind on its own.
+		if step.kind != KUBE_GREEN_KIND && step.kind != genericStepKind && !job.includesKind(step.kind) {
+			log.Printf("Skipping %s, filtered by kind\n", step.kind)
+			continue
 		}
-		return err
-	}
-
-	errObj = ClonePDB(clientset, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		log.Printf("Error cloning PDB: %v\n", err)
-		// Remove the Target Namespace
-		err := RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
+		job.setCurrentKind(step.kind)
+		errObj := job.aborted()
+		if errObj == nil {
+			errObj = step.clone()
+		}
+		if errObj != nil {
+			log.Printf("Error cloning %s: %v\n", step.kind, errObj.Message)
+			if job.dryRun() {
+				// Nothing was created, so there is nothing to roll back
+				return errObj
+			}
+			if !createdNamespace {
+				// The namespace existed before the job, only the objects cloned into it are removed
+				if err := rollbackClonedObjects(targetDynamicClient, targetNamespace, job); err != nil {
+					return &Error{
+						Code:    http.StatusInternalServerError,
+						Message: fmt.Sprintf("Error rolling back clone into %s: %v\n", targetNamespace, err.Message),
+					}
+				}
+				return errObj
 			}
-		}
-		return err
-	}
-
-	errObj = CloneHPA(clientset, sourceNamespace, targetNamespace)
-	if errObj != nil {
-		log.Printf("Error cloning HPA: %v\n", err)
-		// Remove the Target Namespace
-		err := RemoveNamespace(clientset, targetNamespace)
-		if err != nil {
-			return &Error{
-				Code:    http.StatusInternalServerError,
-				Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err),
+			// Remove the Target Namespace
+			// TODO: Probably move the namespace deletion to a go routine for returning faster?
+			err := RemoveNamespace(targetClientset, targetDynamicClient, targetNamespace)
+			if err != nil {
+				return &Error{
+					Code:    http.StatusInternalServerError,
+					Message: fmt.Sprintf("Error removing namespace %s: %v\n", targetNamespace, err.Message),
+				}
 			}
+			return errObj
 		}
 	}
 
@@ -1144,7 +1513,7 @@ func findContainerIndex(deployment *appsv1.Deployment, containerName string) int
 }
 
 // Helper function to apply Kube Green annotations to a namespace
-func applyKubeGreen(clientset *kubernetes.Clientset, dynamicClientSet *dynamic.DynamicClient, clonedNamespace string) *Error {
+func applyKubeGreen(clientset *kubernetes.Clientset, dynamicClientSet dynamic.Interface, clonedNamespace string, job *CloneJob) *Error {
 	// Define the SleepInfo CR object
 	name := fmt.Sprintf("%s-sleepinfo", clonedNamespace)
 	unstructuredMap := map[string]interface{}{
@@ -1168,13 +1537,16 @@ func applyKubeGreen(clientset *kubernetes.Clientset, dynamicClientSet *dynamic.D
 	restClient := dynamicClientSet.Resource(gvr)
 
 	// Create the resource using the dynamic client
-	_, err := restClient.Namespace(clonedNamespace).Create(context.TODO(), unstructuredObj, metav1.CreateOptions{})
-	if err != nil {
-		log.Printf("Error Creating Kube Green Annotation %s For Namespace:s, Err: %v\n", clonedNamespace, err)
-		return &Error{
-			Code:    http.StatusInternalServerError,
-			Message: err.Error(),
-		}
+	created, errObj := job.createResource(gvr, KUBE_GREEN_KIND, name, nil, unstructuredMap["spec"].(map[string]interface{}), func(opts metav1.CreateOptions) error {
+		_, err := restClient.Namespace(clonedNamespace).Create(context.TODO(), unstructuredObj, opts)
+		return err
+	})
+	if errObj != nil {
+		log.Printf("Error Creating Kube Green Annotation %s For Namespace:%s, Err: %v\n", name, clonedNamespace, errObj.Message)
+		return errObj
+	}
+	if !created {
+		return nil
 	}
 	/*createdSleepInfo, err := clientset.KubegreenV1alpha1().SleepInfos(clonedNamespace).Create(context.TODO(), sleepInfoObj, metav1.CreateOptions{})
 	if err != nil {
@@ -1185,5 +1557,6 @@ func applyKubeGreen(clientset *kubernetes.Clientset, dynamicClientSet *dynamic.D
 		}
 	}*/
 	fmt.Printf("Created SleepInfo resource:%+v for namespace %+v\n", name, clonedNamespace)
+	job.record(KUBE_GREEN_KIND, name, ObjectStatusReady, "")
 	return nil
 }
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"slices"
 	"strconv"
 	"strings"
 
@@ -18,7 +17,6 @@ import (
 )
 
 var (
-	excludeSecretPrefixes    = []string{"sh.helm.release"}
 	excludeConfigMapPrefixes = []string{"kube-root-ca.crt"}
 )
 
@@ -53,7 +51,7 @@ type DeploymentDetail struct {
 	POD        string      `json:"pod"`
 	App        string      `json:"app"`
 	Containers []Container `json:"containers"`
-	Replicas   *int32      `json:replicas`
+	Replicas   *int32      `json:"replicas"`
 }
 
 type DeploymentContainers struct {
@@ -129,7 +127,7 @@ func GetDeploymentForNS(clientset *kubernetes.Clientset, namespace string) ([]De
 }
 
 func GetDeploymentYaml(clientset *kubernetes.Clientset, namespace string) (DeploymentContainers, *Error) {
-	deployments, err := getDeploymentsForNS(clientset, namespace)
+	deployments, err := getDeploymentsForNS(clientset, namespace, metav1.ListOptions{})
 	deploymentContainers := DeploymentContainers{}
 	if err != nil {
 		return deploymentContainers, err
@@ -169,7 +167,7 @@ func GetDeploymentYaml(clientset *kubernetes.Clientset, namespace string) (Deplo
 }
 
 func GetSecretYaml(clientset *kubernetes.Clientset, namespace string) ([]Secret, *Error) {
-	secrets, err := getSecretsforNS(clientset, namespace)
+	secrets, err := getSecretsforNS(clientset, namespace, metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -178,31 +176,12 @@ func GetSecretYaml(clientset *kubernetes.Clientset, namespace string) ([]Secret,
 	secretData := make([]Secret, 0)
 
 	for _, secret := range secrets.Items {
-		proceed := true
 		errObj := validateSecretEliblity(clientset, &secret)
 		if errObj != nil {
 			continue
 		}
-		for _, name := range excludeSecretPrefixes {
-			if strings.HasPrefix(secret.Name, name) {
-				proceed = false
-				continue
-			}
-		}
-		// Ignore Kube green secrets as deployed by kube green operator
-		ownerReferences := secret.OwnerReferences
-		for _, ref := range ownerReferences {
-			if ref.APIVersion == KUBE_GREEN_API_VERSION && ref.Kind == KUBE_GREEN_KIND {
-				proceed = false
-				continue
-			}
-		}
-
-		if !proceed {
-			continue
-		}
-
-		if slices.Contains(excludeSecretPrefixes, secret.Name) {
+		// Secrets never cloned, like Helm releases and kube-green's, are not shown
+		if secretSkipReason(&secret) != "" {
 			continue
 		}
 		dataMap := make(map[string]string)
@@ -229,7 +208,7 @@ func GetSecretYaml(clientset *kubernetes.Clientset, namespace string) ([]Secret,
 }
 
 func GetConfigMapYaml(clientset *kubernetes.Clientset, namespace string) ([]ConfigMap, *Error) {
-	configMaps, err := getconfigmapforNS(clientset, namespace)
+	configMaps, err := getconfigmapforNS(clientset, namespace, metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -312,6 +291,8 @@ func PatchDeploymentImage(clientset *kubernetes.Clientset, namespace string, dep
 			"path":  fmt.Sprintf("/spec/template/spec/containers/%d/image", containerIndex),
 			"value": image,
 		},
+		// Keep the patched image when the clone is resynced from its source
+		overridesPatch(deployment.Annotations, containerImageOverride("spec.template.spec", containerName)),
 	}
 
 	// Marshal the patch to JSON
@@ -352,7 +333,9 @@ func PatchSecret(clientset *kubernetes.Clientset, namespace string, secretName s
 	}
 
 	encodedData := make(map[string]string)
+	overrides := make([]string, 0, len(data))
 	for k, v := range data {
+		overrides = append(overrides, dataOverride(k))
 		strValue := fmt.Sprintf("%v", v)
 		// Check if the value is a float (json numbers are treated as float64 in Go), and convert to string without scientific notation
 		if floatValue, ok := v.(float64); ok {
@@ -368,6 +351,7 @@ func PatchSecret(clientset *kubernetes.Clientset, namespace string, secretName s
 			"path":  "/data",
 			"value": encodedData,
 		},
+		overridesPatch(secret.Annotations, overrides...),
 	}
 	// Don't enable this except for debugging as this will leak all the secrets in the logs otherwise
 	//log.Printf("Patch: %v", patch)
@@ -410,6 +394,10 @@ func PatchConfigMap(clientset *kubernetes.Clientset, namespace string, configMap
 		return errObj
 	}
 
+	overrides := make([]string, 0, len(data))
+	for k := range data {
+		overrides = append(overrides, dataOverride(k))
+	}
 	// Construct the patch to update the image
 	patch := []map[string]interface{}{
 		{
@@ -417,6 +405,7 @@ func PatchConfigMap(clientset *kubernetes.Clientset, namespace string, configMap
 			"path":  "/data",
 			"value": data,
 		},
+		overridesPatch(configMap.Annotations, overrides...),
 	}
 	log.Printf("Patch: %v", patch)
 
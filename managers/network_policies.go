@@ -0,0 +1,329 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// Label set on every namespace by the API server, used by namespaceSelectors to select a namespace by name
+	namespaceNameLabel = "kubernetes.io/metadata.name"
+	// Name of the NetworkPolicy that keeps a clone from calling production namespaces
+	isolationPolicyName = "cloner-isolation"
+)
+
+// NetworkIsolation adds a NetworkPolicy to a clone that only allows egress to namespaces that aren't
+// production namespaces. The source namespace is always a production namespace.
+type NetworkIsolation struct {
+	// Production namespaces by name
+	Namespaces []string `json:"namespaces"`
+	// Production namespaces by label selector, e.g. "env=production", resolved when the clone is created
+	NamespaceSelector string `json:"namespaceSelector"`
+	// Destinations outside of the cluster the clone may call, none by default. Some network plugins,
+	// e.g. Calico and Cilium, also match pod IPs against these, so a range covering the pod network lets
+	// traffic to production namespaces through.
+	AllowedCIDRs []string `json:"allowedCIDRs"`
+}
+
+func (n *NetworkIsolation) validate() *Error {
+	if n == nil {
+		return nil
+	}
+	if n.NamespaceSelector != "" {
+		if _, err := labels.Parse(n.NamespaceSelector); err != nil {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid networkIsolation.namespaceSelector %q: %v", n.NamespaceSelector, err),
+			}
+		}
+	}
+	for _, cidr := range n.AllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid networkIsolation.allowedCIDRs entry %q: %v", cidr, err),
+			}
+		}
+	}
+	return nil
+}
+
+// productionNamespaces resolves the namespaces a clone is isolated from, sorted by name
+func (n *NetworkIsolation) productionNamespaces(clientset *kubernetes.Clientset, sourceNamespace string) ([]string, *Error) {
+	namespaces := []string{sourceNamespace}
+	for _, namespace := range n.Namespaces {
+		if !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	if n.NamespaceSelector != "" {
+		selected, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{LabelSelector: n.NamespaceSelector})
+		if err != nil {
+			return nil, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error listing production namespaces %q: %v", n.NamespaceSelector, err),
+			}
+		}
+		for _, namespace := range selected.Items {
+			if !slices.Contains(namespaces, namespace.Name) {
+				namespaces = append(namespaces, namespace.Name)
+			}
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// rewriteNamespaceSelector moves a namespaceSelector selecting the source namespace by name to the target
+func rewriteNamespaceSelector(selector *metav1.LabelSelector, sourceNamespace, targetNamespace, path string, changes *rewriteLog) {
+	if selector == nil {
+		return
+	}
+	if selector.MatchLabels[namespaceNameLabel] == sourceNamespace {
+		selector.MatchLabels[namespaceNameLabel] = targetNamespace
+		changes.record(path+".matchLabels["+namespaceNameLabel+"]", sourceNamespace, targetNamespace)
+	}
+	for i, expression := range selector.MatchExpressions {
+		if expression.Key != namespaceNameLabel {
+			continue
+		}
+		for j, value := range expression.Values {
+			if value == sourceNamespace {
+				selector.MatchExpressions[i].Values[j] = targetNamespace
+				changes.record(fmt.Sprintf("%s.matchExpressions[%d].values[%d]", path, i, j), sourceNamespace, targetNamespace)
+			}
+		}
+	}
+}
+
+// selectsNamespaceByName returns the namespace a peer selects by name, "" when it doesn't select a single namespace
+func selectsNamespaceByName(peer networkingv1.NetworkPolicyPeer) string {
+	if peer.NamespaceSelector == nil {
+		return ""
+	}
+	return peer.NamespaceSelector.MatchLabels[namespaceNameLabel]
+}
+
+// excludeNamespaces adds a requirement to a namespaceSelector that leaves out namespaces by name
+func excludeNamespaces(selector *metav1.LabelSelector, namespaces []string) {
+	selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+		Key:      namespaceNameLabel,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   append([]string(nil), namespaces...),
+	})
+}
+
+// allowedIPBlock returns whether an ipBlock lies within one of the allowed CIDRs
+func allowedIPBlock(block *networkingv1.IPBlock, allowedCIDRs []string) bool {
+	_, network, err := net.ParseCIDR(block.CIDR)
+	if err != nil {
+		return false
+	}
+	ones, bits := network.Mask.Size()
+	for _, cidr := range allowedCIDRs {
+		_, allowed, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		allowedOnes, allowedBits := allowed.Mask.Size()
+		if bits == allowedBits && ones >= allowedOnes && allowed.Contains(network.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// isolationPeers are the egress peers of an isolated clone: namespaces that aren't production namespaces and
+// the allowed CIDRs
+func isolationPeers(production, allowedCIDRs []string) []networkingv1.NetworkPolicyPeer {
+	selector := &metav1.LabelSelector{}
+	excludeNamespaces(selector, production)
+	peers := []networkingv1.NetworkPolicyPeer{{NamespaceSelector: selector}}
+	for _, cidr := range allowedCIDRs {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+	}
+	return peers
+}
+
+// rewriteNetworkPolicySpec rewrites the peers of a cloned NetworkPolicy. NetworkPolicies add up, so with
+// production namespaces to isolate from no egress rule may allow more than the isolation policy: rules
+// without peers are limited to the isolation peers, every egress namespaceSelector leaves out the production
+// namespaces, ipBlocks outside of the allowed CIDRs are dropped, along with rules left without peers.
+func rewriteNetworkPolicySpec(spec *networkingv1.NetworkPolicySpec, sourceNamespace, targetNamespace string, production, allowedCIDRs []string, changes *rewriteLog) {
+	for i := range spec.Ingress {
+		for j := range spec.Ingress[i].From {
+			path := fmt.Sprintf("spec.ingress[%d].from[%d].namespaceSelector", i, j)
+			rewriteNamespaceSelector(spec.Ingress[i].From[j].NamespaceSelector, sourceNamespace, targetNamespace, path, changes)
+		}
+	}
+	egress := spec.Egress[:0]
+	for i, rule := range spec.Egress {
+		if len(production) > 0 && len(rule.To) == 0 {
+			// A rule without peers allows every destination
+			rule.To = isolationPeers(production, allowedCIDRs)
+			changes.record(fmt.Sprintf("spec.egress[%d].to", i), "all destinations", "isolation peers")
+			egress = append(egress, rule)
+			continue
+		}
+		peers := rule.To[:0]
+		for j, peer := range rule.To {
+			path := fmt.Sprintf("spec.egress[%d].to[%d]", i, j)
+			if namespace := selectsNamespaceByName(peer); namespace != sourceNamespace && slices.Contains(production, namespace) {
+				changes.record(path, namespace, "")
+				continue
+			}
+			if len(production) > 0 && peer.IPBlock != nil && !allowedIPBlock(peer.IPBlock, allowedCIDRs) {
+				changes.record(path+".ipBlock", peer.IPBlock.CIDR, "")
+				continue
+			}
+			rewriteNamespaceSelector(peer.NamespaceSelector, sourceNamespace, targetNamespace, path+".namespaceSelector", changes)
+			if len(production) > 0 && peer.NamespaceSelector != nil {
+				excludeNamespaces(peer.NamespaceSelector, production)
+				changes.record(fmt.Sprintf("%s.namespaceSelector.matchExpressions[%d]", path, len(peer.NamespaceSelector.MatchExpressions)-1),
+					"", namespaceNameLabel+" NotIn "+strings.Join(production, ","))
+			}
+			peers = append(peers, peer)
+		}
+		if len(rule.To) > 0 && len(peers) == 0 {
+			changes.record(fmt.Sprintf("spec.egress[%d]", i), "only production namespaces or blocked CIDRs", "")
+			continue
+		}
+		rule.To = peers
+		egress = append(egress, rule)
+	}
+	if spec.Egress != nil {
+		spec.Egress = egress
+	}
+}
+
+// isolationPolicy only allows the pods of a clone egress to namespaces that aren't production namespaces
+func isolationPolicy(targetNamespace, sourceNamespace string, production, allowedCIDRs []string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      isolationPolicyName,
+			Namespace: targetNamespace,
+			Annotations: map[string]string{
+				TARGET_NS_ANNOTATION:         sourceNamespace,
+				TARGET_NS_ANNOTATION_ENABLED: "true",
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      []networkingv1.NetworkPolicyEgressRule{{To: isolationPeers(production, allowedCIDRs)}},
+		},
+	}
+}
+
+// CloneNetworkPolicies clones the NetworkPolicies of the source namespace, moving namespaceSelectors that select
+// the source namespace by name to the target. With NetworkIsolation an isolation policy is added as well.
+func CloneNetworkPolicies(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	networkPolicies, err := clientset.NetworkingV1().NetworkPolicies(sourceNamespace).List(context.TODO(), job.listOptions())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Printf("Namespace %s does not have any NetworkPolicies\n", sourceNamespace)
+			return nil
+		}
+		log.Printf("Error checking for NetworkPolicies: %v\n", err)
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	isolation := job.networkIsolation()
+	var production, allowedCIDRs []string
+	if isolation != nil {
+		allowedCIDRs = isolation.AllowedCIDRs
+		var errObj *Error
+		if production, errObj = isolation.productionNamespaces(clientset, sourceNamespace); errObj != nil {
+			return errObj
+		}
+	}
+
+	for _, networkPolicy := range networkPolicies.Items {
+		if !job.matchesName(networkPolicy.Name) || networkPolicy.Name == isolationPolicyName {
+			continue
+		}
+		_, err := targetClientset.NetworkingV1().NetworkPolicies(targetNamespace).Get(context.TODO(), networkPolicy.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error checking for existing NetworkPolicy %s: %v", networkPolicy.Name, err),
+			}
+		} else if err == nil {
+			log.Printf("NetworkPolicy %s already exists in %s, skipping creation\n", networkPolicy.Name, targetNamespace)
+			job.skip("NetworkPolicy", networkPolicy.Name, "already exists")
+			continue
+		}
+
+		annotations := make(map[string]string)
+		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+		annotations[TARGET_NETWORK_POLICY_ANNOTATION] = networkPolicy.Name
+		newNetworkPolicy := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        networkPolicy.Name,
+				Namespace:   targetNamespace,
+				Labels:      networkPolicy.Labels,
+				Annotations: annotations,
+			},
+			Spec: *networkPolicy.Spec.DeepCopy(),
+		}
+		changes := newRewriteLog("NetworkPolicy", networkPolicy.Name)
+		rewriteNetworkPolicySpec(&newNetworkPolicy.Spec, sourceNamespace, targetNamespace, production, allowedCIDRs, changes)
+
+		created, errObj := job.create("NetworkPolicy", networkPolicy.Name, annotations, changes.mutations, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.NetworkingV1().NetworkPolicies(targetNamespace).Create(context.TODO(), newNetworkPolicy, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		job.recordRewrites(changes.rewrites)
+		if !created {
+			continue
+		}
+		// NetworkPolicies have no status, they apply as soon as they exist
+		log.Printf("NetworkPolicy %s cloned to namespace %s\n", networkPolicy.Name, targetNamespace)
+		job.record("NetworkPolicy", networkPolicy.Name, ObjectStatusReady, "")
+	}
+
+	if isolation == nil {
+		return nil
+	}
+	policy := isolationPolicy(targetNamespace, sourceNamespace, production, isolation.AllowedCIDRs)
+	_, err = targetClientset.NetworkingV1().NetworkPolicies(targetNamespace).Get(context.TODO(), policy.Name, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error checking for existing NetworkPolicy %s: %v", policy.Name, err),
+		}
+	} else if err == nil {
+		job.skip("NetworkPolicy", policy.Name, "already exists")
+		return nil
+	}
+	mutations := map[string]interface{}{"spec.egress[0].to[0].namespaceSelector.matchExpressions[0].values": production}
+	created, errObj := job.create("NetworkPolicy", policy.Name, policy.Annotations, mutations, func(opts metav1.CreateOptions) error {
+		_, err := targetClientset.NetworkingV1().NetworkPolicies(targetNamespace).Create(context.TODO(), policy, opts)
+		return err
+	})
+	if errObj != nil || !created {
+		return errObj
+	}
+	log.Printf("NetworkPolicy %s isolates %s from %s\n", policy.Name, targetNamespace, strings.Join(production, ", "))
+	job.record("NetworkPolicy", policy.Name, ObjectStatusReady, "isolated from "+strings.Join(production, ", "))
+	return nil
+}
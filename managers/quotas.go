@@ -0,0 +1,237 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// Names of the objects created in a clone from a quota profile
+const (
+	QuotaProfileResourceQuotaName = "cloner-quota"
+	QuotaProfileLimitRangeName    = "cloner-limits"
+)
+
+// QuotaProfile is a named ResourceQuota and LimitRange applied to a clone instead of the source's ones
+type QuotaProfile struct {
+	ResourceQuota *v1.ResourceQuotaSpec `json:"resourceQuota"`
+	LimitRange    *v1.LimitRangeSpec    `json:"limitRange"`
+}
+
+// Quota profiles loaded from -quota-profiles, by name
+var quotaProfiles = struct {
+	sync.RWMutex
+	profiles map[string]QuotaProfile
+}{profiles: map[string]QuotaProfile{}}
+
+// LoadQuotaProfiles reads the quota profiles clone requests can name from a YAML or JSON file, e.g.
+//
+//	small:
+//	  resourceQuota:
+//	    hard: {requests.cpu: "2", requests.memory: 4Gi, pods: "20"}
+//	  limitRange:
+//	    limits:
+//	    - type: Container
+//	      default: {cpu: 500m, memory: 512Mi}
+func LoadQuotaProfiles(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading quota profiles %s: %v", path, err)
+	}
+	profiles := map[string]QuotaProfile{}
+	if err := yaml.UnmarshalStrict(data, &profiles); err != nil {
+		return fmt.Errorf("error parsing quota profiles %s: %v", path, err)
+	}
+
+	quotaProfiles.Lock()
+	defer quotaProfiles.Unlock()
+	quotaProfiles.profiles = profiles
+	for name := range profiles {
+		log.Printf("Registered quota profile %s\n", name)
+	}
+	return nil
+}
+
+func getQuotaProfile(name string) (QuotaProfile, *Error) {
+	quotaProfiles.RLock()
+	defer quotaProfiles.RUnlock()
+	profile, ok := quotaProfiles.profiles[name]
+	if !ok {
+		names := make([]string, 0, len(quotaProfiles.profiles))
+		for profileName := range quotaProfiles.profiles {
+			names = append(names, profileName)
+		}
+		sort.Strings(names)
+		return QuotaProfile{}, &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Unknown quotaProfile %q, available profiles: %v", name, names),
+		}
+	}
+	return profile, nil
+}
+
+// CloneResourceQuotas copies the ResourceQuotas of the source namespace. With a quota profile the profile's
+// quota is created instead.
+func CloneResourceQuotas(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	if name := job.quotaProfile(); name != "" {
+		profile, errObj := getQuotaProfile(name)
+		if errObj != nil || profile.ResourceQuota == nil {
+			return errObj
+		}
+		resourceQuota := &v1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: QuotaProfileResourceQuotaName},
+			Spec:       *profile.ResourceQuota,
+		}
+		return cloneResourceQuota(targetClientset, resourceQuota, sourceNamespace, targetNamespace, name, job)
+	}
+
+	resourceQuotas, err := clientset.CoreV1().ResourceQuotas(sourceNamespace).List(context.TODO(), job.listOptions())
+	if err != nil {
+		log.Printf("Error checking for ResourceQuotas: %v\n", err)
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	for i := range resourceQuotas.Items {
+		resourceQuota := &resourceQuotas.Items[i]
+		if !job.matchesName(resourceQuota.Name) {
+			continue
+		}
+		if errObj := cloneResourceQuota(targetClientset, resourceQuota, sourceNamespace, targetNamespace, "", job); errObj != nil {
+			return errObj
+		}
+	}
+	return nil
+}
+
+func cloneResourceQuota(targetClientset *kubernetes.Clientset, resourceQuota *v1.ResourceQuota, sourceNamespace, targetNamespace, profile string, job *CloneJob) *Error {
+	_, err := targetClientset.CoreV1().ResourceQuotas(targetNamespace).Get(context.TODO(), resourceQuota.Name, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error checking for existing ResourceQuota %s: %v", resourceQuota.Name, err),
+		}
+	} else if err == nil {
+		log.Printf("ResourceQuota %s already exists in %s, skipping creation\n", resourceQuota.Name, targetNamespace)
+		job.skip("ResourceQuota", resourceQuota.Name, "already exists")
+		return nil
+	}
+
+	annotations := make(map[string]string)
+	annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+	annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+	if profile != "" {
+		annotations[TARGET_QUOTA_PROFILE_ANNOTATION] = profile
+	} else {
+		annotations[TARGET_RESOURCE_QUOTA_ANNOTATION] = resourceQuota.Name
+	}
+	newResourceQuota := &v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        resourceQuota.Name,
+			Namespace:   targetNamespace,
+			Labels:      resourceQuota.Labels,
+			Annotations: annotations,
+		},
+		Spec: resourceQuota.Spec,
+	}
+	created, errObj := job.create("ResourceQuota", resourceQuota.Name, annotations, nil, func(opts metav1.CreateOptions) error {
+		_, err := targetClientset.CoreV1().ResourceQuotas(targetNamespace).Create(context.TODO(), newResourceQuota, opts)
+		return err
+	})
+	if errObj != nil || !created {
+		return errObj
+	}
+	log.Printf("ResourceQuota %s cloned to namespace %s\n", resourceQuota.Name, targetNamespace)
+	job.record("ResourceQuota", resourceQuota.Name, ObjectStatusReady, "")
+	return nil
+}
+
+// CloneLimitRanges copies the LimitRanges of the source namespace. With a quota profile the profile's
+// limits are created instead.
+func CloneLimitRanges(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	if name := job.quotaProfile(); name != "" {
+		profile, errObj := getQuotaProfile(name)
+		if errObj != nil || profile.LimitRange == nil {
+			return errObj
+		}
+		limitRange := &v1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{Name: QuotaProfileLimitRangeName},
+			Spec:       *profile.LimitRange,
+		}
+		return cloneLimitRange(targetClientset, limitRange, sourceNamespace, targetNamespace, name, job)
+	}
+
+	limitRanges, err := clientset.CoreV1().LimitRanges(sourceNamespace).List(context.TODO(), job.listOptions())
+	if err != nil {
+		log.Printf("Error checking for LimitRanges: %v\n", err)
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	for i := range limitRanges.Items {
+		limitRange := &limitRanges.Items[i]
+		if !job.matchesName(limitRange.Name) {
+			continue
+		}
+		if errObj := cloneLimitRange(targetClientset, limitRange, sourceNamespace, targetNamespace, "", job); errObj != nil {
+			return errObj
+		}
+	}
+	return nil
+}
+
+func cloneLimitRange(targetClientset *kubernetes.Clientset, limitRange *v1.LimitRange, sourceNamespace, targetNamespace, profile string, job *CloneJob) *Error {
+	_, err := targetClientset.CoreV1().LimitRanges(targetNamespace).Get(context.TODO(), limitRange.Name, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error checking for existing LimitRange %s: %v", limitRange.Name, err),
+		}
+	} else if err == nil {
+		log.Printf("LimitRange %s already exists in %s, skipping creation\n", limitRange.Name, targetNamespace)
+		job.skip("LimitRange", limitRange.Name, "already exists")
+		return nil
+	}
+
+	annotations := make(map[string]string)
+	annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+	annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+	if profile != "" {
+		annotations[TARGET_QUOTA_PROFILE_ANNOTATION] = profile
+	} else {
+		annotations[TARGET_LIMIT_RANGE_ANNOTATION] = limitRange.Name
+	}
+	newLimitRange := &v1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        limitRange.Name,
+			Namespace:   targetNamespace,
+			Labels:      limitRange.Labels,
+			Annotations: annotations,
+		},
+		Spec: limitRange.Spec,
+	}
+	created, errObj := job.create("LimitRange", limitRange.Name, annotations, nil, func(opts metav1.CreateOptions) error {
+		_, err := targetClientset.CoreV1().LimitRanges(targetNamespace).Create(context.TODO(), newLimitRange, opts)
+		return err
+	})
+	if errObj != nil || !created {
+		return errObj
+	}
+	log.Printf("LimitRange %s cloned to namespace %s\n", limitRange.Name, targetNamespace)
+	job.record("LimitRange", limitRange.Name, ObjectStatusReady, "")
+	return nil
+}
@@ -0,0 +1,226 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// rewriteSubjects moves the ServiceAccount subjects of the source namespace to the target namespace. Subjects
+// of other kinds or namespaces are kept as they are.
+func rewriteSubjects(subjects []rbacv1.Subject, sourceNamespace, targetNamespace string, changes *rewriteLog) []rbacv1.Subject {
+	rewritten := make([]rbacv1.Subject, len(subjects))
+	for i, subject := range subjects {
+		if subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace == sourceNamespace {
+			subject.Namespace = targetNamespace
+			changes.record(fmt.Sprintf("subjects[%d].namespace", i), sourceNamespace, targetNamespace)
+		}
+		rewritten[i] = subject
+	}
+	return rewritten
+}
+
+// grantsToServiceAccounts tells whether a binding has a ServiceAccount subject in namespace
+func grantsToServiceAccounts(subjects []rbacv1.Subject, namespace string) bool {
+	for _, subject := range subjects {
+		if subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func CloneRoles(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	roles, err := clientset.RbacV1().Roles(sourceNamespace).List(context.TODO(), job.listOptions())
+	if err != nil {
+		log.Printf("Error checking for Roles: %v\n", err)
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	for _, role := range roles.Items {
+		if !job.matchesName(role.Name) {
+			continue
+		}
+		_, err := targetClientset.RbacV1().Roles(targetNamespace).Get(context.TODO(), role.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error checking for existing Role %s: %v", role.Name, err),
+			}
+		} else if err == nil {
+			log.Printf("Role %s already exists in %s, skipping creation\n", role.Name, targetNamespace)
+			job.skip("Role", role.Name, "already exists")
+			continue
+		}
+
+		annotations := make(map[string]string)
+		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+		annotations[TARGET_ROLE_ANNOTATION] = role.Name
+		newRole := &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        role.Name,
+				Namespace:   targetNamespace,
+				Labels:      role.Labels,
+				Annotations: annotations,
+			},
+			Rules: role.Rules,
+		}
+		created, errObj := job.create("Role", role.Name, annotations, nil, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.RbacV1().Roles(targetNamespace).Create(context.TODO(), newRole, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		if !created {
+			continue
+		}
+		log.Printf("Role %s cloned to namespace %s\n", role.Name, targetNamespace)
+		job.record("Role", role.Name, ObjectStatusReady, "")
+	}
+	return nil
+}
+
+func CloneRoleBindings(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	roleBindings, err := clientset.RbacV1().RoleBindings(sourceNamespace).List(context.TODO(), job.listOptions())
+	if err != nil {
+		log.Printf("Error checking for RoleBindings: %v\n", err)
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	for _, roleBinding := range roleBindings.Items {
+		if !job.matchesName(roleBinding.Name) {
+			continue
+		}
+		_, err := targetClientset.RbacV1().RoleBindings(targetNamespace).Get(context.TODO(), roleBinding.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error checking for existing RoleBinding %s: %v", roleBinding.Name, err),
+			}
+		} else if err == nil {
+			log.Printf("RoleBinding %s already exists in %s, skipping creation\n", roleBinding.Name, targetNamespace)
+			job.skip("RoleBinding", roleBinding.Name, "already exists")
+			continue
+		}
+
+		annotations := make(map[string]string)
+		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+		annotations[TARGET_ROLE_BINDING_ANNOTATION] = roleBinding.Name
+		changes := newRewriteLog("RoleBinding", roleBinding.Name)
+		newRoleBinding := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        roleBinding.Name,
+				Namespace:   targetNamespace,
+				Labels:      roleBinding.Labels,
+				Annotations: annotations,
+			},
+			// A Role reference resolves to the cloned Role, ClusterRoles are shared
+			RoleRef:  roleBinding.RoleRef,
+			Subjects: rewriteSubjects(roleBinding.Subjects, sourceNamespace, targetNamespace, changes),
+		}
+		created, errObj := job.create("RoleBinding", roleBinding.Name, annotations, changes.mutations, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.RbacV1().RoleBindings(targetNamespace).Create(context.TODO(), newRoleBinding, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		job.recordRewrites(changes.rewrites)
+		if !created {
+			continue
+		}
+		log.Printf("RoleBinding %s cloned to namespace %s\n", roleBinding.Name, targetNamespace)
+		job.record("RoleBinding", roleBinding.Name, ObjectStatusReady, "")
+	}
+	return nil
+}
+
+// CloneClusterRoleBindings grants the ClusterRoles bound to ServiceAccounts of the source namespace by
+// ClusterRoleBindings to the cloned ServiceAccounts. Each binding becomes a RoleBinding of the ClusterRole in the
+// target namespace, so the clone only gets the namespaced permissions, and goes away along with the namespace.
+func CloneClusterRoleBindings(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	if job == nil || !job.Options.CloneClusterRoleBindings {
+		return nil
+	}
+	targetClientset := job.targetClientset(clientset)
+	clusterRoleBindings, err := clientset.RbacV1().ClusterRoleBindings().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Error checking for ClusterRoleBindings: %v\n", err)
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	for _, clusterRoleBinding := range clusterRoleBindings.Items {
+		if !grantsToServiceAccounts(clusterRoleBinding.Subjects, sourceNamespace) {
+			continue
+		}
+		name := clusterRoleBinding.Name
+		_, err := targetClientset.RbacV1().RoleBindings(targetNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error checking for existing RoleBinding %s: %v", name, err),
+			}
+		} else if err == nil {
+			log.Printf("RoleBinding %s already exists in %s, skipping creation\n", name, targetNamespace)
+			job.skip("RoleBinding", name, "already exists")
+			continue
+		}
+
+		var subjects []rbacv1.Subject
+		for _, subject := range clusterRoleBinding.Subjects {
+			if subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace == sourceNamespace {
+				subjects = append(subjects, subject)
+			}
+		}
+		annotations := make(map[string]string)
+		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+		annotations[TARGET_CLUSTER_ROLE_BINDING_ANNOTATION] = clusterRoleBinding.Name
+		changes := newRewriteLog("RoleBinding", name)
+		newRoleBinding := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   targetNamespace,
+				Labels:      clusterRoleBinding.Labels,
+				Annotations: annotations,
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     clusterRoleBinding.RoleRef.Name,
+			},
+			Subjects: rewriteSubjects(subjects, sourceNamespace, targetNamespace, changes),
+		}
+		created, errObj := job.create("RoleBinding", name, annotations, changes.mutations, func(opts metav1.CreateOptions) error {
+			_, err := targetClientset.RbacV1().RoleBindings(targetNamespace).Create(context.TODO(), newRoleBinding, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		job.recordRewrites(changes.rewrites)
+		if !created {
+			continue
+		}
+		log.Printf("ClusterRoleBinding %s cloned as RoleBinding to namespace %s\n", clusterRoleBinding.Name, targetNamespace)
+		job.record("RoleBinding", name, ObjectStatusReady, "")
+	}
+	return nil
+}
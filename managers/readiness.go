@@ -0,0 +1,261 @@
+package managers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// ReadinessPolicy decides how long a clone waits for each created workload and service before moving on
+type ReadinessPolicy string
+
+const (
+	// Don't wait at all
+	ReadinessNone ReadinessPolicy = "none"
+	// Wait until the object has been picked up by its controller
+	ReadinessCreated ReadinessPolicy = "created"
+	// Wait until every replica is ready and services have their ClusterIP, the default
+	ReadinessReady ReadinessPolicy = "ready"
+)
+
+// Time to wait for an object of each kind, overridable per clone with readinessTimeouts.
+// Namespace is the time to wait for a namespace to be deleted, VolumeSnapshot for a snapshot of a source claim.
+var DefaultReadinessTimeouts = map[string]time.Duration{
+	"Deployment":     10 * time.Minute,
+	"StatefulSet":    15 * time.Minute,
+	"Service":        2 * time.Minute,
+	"Namespace":      10 * time.Minute,
+	"VolumeSnapshot": 10 * time.Minute,
+}
+
+func (p ReadinessPolicy) validate() *Error {
+	switch p {
+	case "", ReadinessNone, ReadinessCreated, ReadinessReady:
+		return nil
+	}
+	return &Error{
+		Code:    http.StatusBadRequest,
+		Message: fmt.Sprintf("Invalid readiness %q, expected one of none, created or ready", p),
+	}
+}
+
+// readinessFailure is returned by a wait condition when the object can never become ready
+type readinessFailure struct {
+	message string
+}
+
+func (f *readinessFailure) Error() string {
+	return f.message
+}
+
+// nameListWatch lists and watches a single object by name
+func nameListWatch(name string, list func(metav1.ListOptions) (runtime.Object, error), watchFunc func(metav1.ListOptions) (watch.Interface, error)) *cache.ListWatch {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return list(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return watchFunc(options)
+		},
+	}
+}
+
+// waitUntil watches an object until condition is met. It gives up when the object is deleted, the
+// condition returns a readinessFailure, the timeout expires or ctx is cancelled.
+func waitUntil(ctx context.Context, timeout time.Duration, kind, name, state string, lw cache.ListerWatcher, objType runtime.Object, precondition watchtools.PreconditionFunc, condition func(watch.Event) (bool, error)) *Error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	_, err := watchtools.UntilWithSync(waitCtx, lw, objType, precondition, condition)
+	if err == nil {
+		return nil
+	}
+	var failure *readinessFailure
+	switch {
+	case errors.As(err, &failure):
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: failure.message,
+		}
+	case ctx.Err() != nil:
+		return &Error{
+			Code:    http.StatusConflict,
+			Message: fmt.Sprintf("Cancelled while waiting for %s %s to be %s", kind, name, state),
+		}
+	case waitCtx.Err() != nil:
+		return &Error{
+			Code:    http.StatusGatewayTimeout,
+			Message: fmt.Sprintf("Timed out after %s waiting for %s %s to be %s", timeout, kind, name, state),
+		}
+	}
+	return &Error{
+		Code:    http.StatusInternalServerError,
+		Message: fmt.Sprintf("Error waiting for %s %s to be %s: %v", kind, name, state, err),
+	}
+}
+
+// waitForObject applies the job's readiness policy to a created object and records the outcome on the job.
+// observed tells whether the controller has picked up the object, ready whether it is ready along with a
+// progress message.
+func waitForObject(job *CloneJob, kind, name string, lw cache.ListerWatcher, objType runtime.Object, observed func(runtime.Object) bool, ready func(runtime.Object) (bool, string, error)) *Error {
+	policy := job.readinessPolicy()
+	if policy == ReadinessNone {
+		return nil
+	}
+	state := "ready"
+	if policy == ReadinessCreated {
+		state = "observed"
+	}
+	message := ""
+	errObj := waitUntil(job.context(), job.readinessTimeout(kind), kind, name, state, lw, objType, nil, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, &readinessFailure{fmt.Sprintf("%s %s was deleted while waiting for it to be %s", kind, name, state)}
+		}
+		if !observed(event.Object) {
+			return false, nil
+		}
+		if policy == ReadinessCreated {
+			return true, nil
+		}
+		isReady, progress, err := ready(event.Object)
+		if err != nil {
+			return false, err
+		}
+		message = progress
+		if !isReady {
+			log.Printf("Waiting for %s %s to be ready (%s)...\n", kind, name, progress)
+			job.record(kind, name, ObjectStatusCreated, progress)
+		}
+		return isReady, nil
+	})
+	if errObj != nil {
+		job.record(kind, name, ObjectStatusFailed, errObj.Message)
+		return errObj
+	}
+	if policy == ReadinessReady {
+		log.Printf("%s %s is ready %s\n", kind, name, message)
+		job.record(kind, name, ObjectStatusReady, message)
+	}
+	return nil
+}
+
+func waitForDeployment(clientset kubernetes.Interface, namespace, name string, job *CloneJob) *Error {
+	deployments := clientset.AppsV1().Deployments(namespace)
+	lw := nameListWatch(name,
+		func(options metav1.ListOptions) (runtime.Object, error) {
+			return deployments.List(job.context(), options)
+		},
+		func(options metav1.ListOptions) (watch.Interface, error) {
+			return deployments.Watch(job.context(), options)
+		})
+	return waitForObject(job, "Deployment", name, lw, &appsv1.Deployment{},
+		func(obj runtime.Object) bool {
+			deployment := obj.(*appsv1.Deployment)
+			return deployment.Status.ObservedGeneration >= deployment.Generation
+		},
+		func(obj runtime.Object) (bool, string, error) {
+			deployment := obj.(*appsv1.Deployment)
+			for _, condition := range deployment.Status.Conditions {
+				if condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == v1.ConditionTrue {
+					return false, "", &readinessFailure{fmt.Sprintf("Deployment %s has failed: %s", name, condition.Reason)}
+				}
+			}
+			desired := int32(1)
+			if deployment.Spec.Replicas != nil {
+				desired = *deployment.Spec.Replicas
+			}
+			ready := deployment.Status.ReadyReplicas
+			return ready == desired, fmt.Sprintf("%d/%d replicas ready", ready, desired), nil
+		})
+}
+
+func waitForStatefulSet(clientset kubernetes.Interface, namespace, name string, job *CloneJob) *Error {
+	statefulSets := clientset.AppsV1().StatefulSets(namespace)
+	lw := nameListWatch(name,
+		func(options metav1.ListOptions) (runtime.Object, error) {
+			return statefulSets.List(job.context(), options)
+		},
+		func(options metav1.ListOptions) (watch.Interface, error) {
+			return statefulSets.Watch(job.context(), options)
+		})
+	return waitForObject(job, "StatefulSet", name, lw, &appsv1.StatefulSet{},
+		func(obj runtime.Object) bool {
+			statefulSet := obj.(*appsv1.StatefulSet)
+			return statefulSet.Status.ObservedGeneration >= statefulSet.Generation
+		},
+		func(obj runtime.Object) (bool, string, error) {
+			statefulSet := obj.(*appsv1.StatefulSet)
+			if hasStatefulSetUpdateFailure(statefulSet) {
+				return false, "", &readinessFailure{fmt.Sprintf("StatefulSet %s has failed: %s", name, getStatefulSetFailureReason(statefulSet))}
+			}
+			desired := int32(1)
+			if statefulSet.Spec.Replicas != nil {
+				desired = *statefulSet.Spec.Replicas
+			}
+			ready := statefulSet.Status.ReadyReplicas
+			return ready == desired, fmt.Sprintf("%d/%d replicas ready", ready, desired), nil
+		})
+}
+
+func waitForService(clientset kubernetes.Interface, namespace, name string, job *CloneJob) *Error {
+	services := clientset.CoreV1().Services(namespace)
+	lw := nameListWatch(name,
+		func(options metav1.ListOptions) (runtime.Object, error) { return services.List(job.context(), options) },
+		func(options metav1.ListOptions) (watch.Interface, error) {
+			return services.Watch(job.context(), options)
+		})
+	return waitForObject(job, "Service", name, lw, &v1.Service{},
+		func(obj runtime.Object) bool { return true },
+		func(obj runtime.Object) (bool, string, error) {
+			service := obj.(*v1.Service)
+			// ExternalName services never get a ClusterIP
+			if service.Spec.Type == v1.ServiceTypeExternalName {
+				return true, "", nil
+			}
+			if service.Spec.ClusterIP == "" {
+				return false, "waiting for ClusterIP", nil
+			}
+			return true, "ClusterIP " + service.Spec.ClusterIP, nil
+		})
+}
+
+// waitForNamespaceDeletion waits until a namespace is gone, progress is called with the namespace every
+// time it changes while it terminates
+func waitForNamespaceDeletion(ctx context.Context, clientset kubernetes.Interface, namespace string, timeout time.Duration, progress func(*v1.Namespace)) *Error {
+	namespaces := clientset.CoreV1().Namespaces()
+	lw := nameListWatch(namespace,
+		func(options metav1.ListOptions) (runtime.Object, error) { return namespaces.List(ctx, options) },
+		func(options metav1.ListOptions) (watch.Interface, error) { return namespaces.Watch(ctx, options) })
+	return waitUntil(ctx, timeout, "Namespace", namespace, "deleted", lw, &v1.Namespace{},
+		func(store cache.Store) (bool, error) {
+			// Already gone before the watch started
+			return len(store.List()) == 0, nil
+		},
+		func(event watch.Event) (bool, error) {
+			if event.Type == watch.Deleted {
+				return true, nil
+			}
+			if ns, ok := event.Object.(*v1.Namespace); ok {
+				log.Printf("Waiting for namespace %s to be deleted...\n", namespace)
+				if progress != nil {
+					progress(ns)
+				}
+			}
+			return false, nil
+		})
+}
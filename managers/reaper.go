@@ -0,0 +1,161 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const reaperComponent = "k8s-namespace-cloner"
+
+// StartReaper starts a go routine that deletes expired clones every interval, in the cluster of
+// clientset and in every registered target cluster. Clones expiring within warnBefore get a
+// warning event, once per expiry time.
+func StartReaper(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, interval, warnBefore time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			reapExpiredNamespaces("", clientset, dynamicClient, warnBefore)
+			for _, name := range ListClusters() {
+				cluster, errObj := GetCluster(name)
+				if errObj != nil {
+					continue
+				}
+				reapExpiredNamespaces(name, cluster.Clientset, cluster.DynamicClient, warnBefore)
+			}
+			<-ticker.C
+		}
+	}()
+	log.Printf("Started clone reaper, checking every %s\n", interval)
+}
+
+// reapExpiredNamespaces deletes the expired clones of a single cluster
+func reapExpiredNamespaces(cluster string, clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, warnBefore time.Duration) {
+	namespaces, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Reaper: error listing namespaces in cluster %q: %v\n", cluster, err)
+		return
+	}
+	now := time.Now()
+	for _, namespace := range namespaces.Items {
+		// Only clones are ever reaped, never a namespace that is a clone source
+		if validateNamespaceDeletion(&namespace) != nil {
+			continue
+		}
+		value, ok := namespace.Annotations[TARGET_EXPIRES_AT_ANNOTATION]
+		if !ok || namespace.DeletionTimestamp != nil {
+			continue
+		}
+		expiresAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			log.Printf("Reaper: ignoring namespace %s with invalid %s annotation %q\n", namespace.Name, TARGET_EXPIRES_AT_ANNOTATION, value)
+			continue
+		}
+		if !now.Before(expiresAt) {
+			log.Printf("Reaper: namespace %s in cluster %q expired at %s, deleting\n", namespace.Name, cluster, value)
+			status, errObj := startDeletion(clientset, dynamicClient, cluster, namespace.Name)
+			if errObj != nil {
+				// A deletion still running from an earlier tick or a DELETE request
+				log.Printf("Reaper: not deleting namespace %s: %s\n", namespace.Name, errObj.Message)
+				continue
+			}
+			log.Printf("Reaper: started deletion %s of namespace %s\n", status.ID, namespace.Name)
+			continue
+		}
+		if expiresAt.Sub(now) <= warnBefore && namespace.Annotations[TARGET_EXPIRY_WARNED_ANNOTATION] != value {
+			warnExpiry(clientset, namespace.Name, value, expiresAt.Sub(now))
+		}
+	}
+}
+
+// warnExpiry records a warning event on a clone that is about to expire. The warned expiry time is
+// stored on the namespace so the warning is sent again only when the expiry is extended.
+func warnExpiry(clientset *kubernetes.Clientset, namespace, expiresAt string, remaining time.Duration) {
+	message := fmt.Sprintf("Cloned namespace %s expires at %s (in %s) and will be deleted, extend it with POST /api/v1/namespaces/%s/extend",
+		namespace, expiresAt, remaining.Round(time.Minute), namespace)
+	log.Printf("Reaper: %s\n", message)
+	now := metav1.Now()
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: namespace + ".",
+			Namespace:    namespace,
+		},
+		InvolvedObject: v1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+			Name:       namespace,
+		},
+		Reason:         "CloneExpiring",
+		Message:        message,
+		Type:           v1.EventTypeWarning,
+		Source:         v1.EventSource{Component: reaperComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if _, err := clientset.CoreV1().Events(namespace).Create(context.TODO(), event, metav1.CreateOptions{}); err != nil {
+		log.Printf("Reaper: error creating expiry event in %s: %v\n", namespace, err)
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, TARGET_EXPIRY_WARNED_ANNOTATION, expiresAt)
+	if _, err := clientset.CoreV1().Namespaces().Patch(context.TODO(), namespace, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		log.Printf("Reaper: error recording expiry warning on %s: %v\n", namespace, err)
+	}
+}
+
+// ExtendNamespaceExpiry moves the expiry of a clone. A TTL is added to the current expiry (or to now
+// when the clone has already expired or has no expiry), expiresAt replaces it.
+func ExtendNamespaceExpiry(clientset *kubernetes.Clientset, namespace, ttl string, expiresAt *time.Time) (time.Time, *Error) {
+	if ttl == "" && expiresAt == nil {
+		return time.Time{}, &Error{
+			Code:    http.StatusBadRequest,
+			Message: "One of ttl and expiresAt is required",
+		}
+	}
+	ns, err := clientset.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return time.Time{}, &Error{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Namespace %s not found", namespace),
+			}
+		}
+		return time.Time{}, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	if ns.Annotations[TARGET_NS_ANNOTATION_ENABLED] != "true" {
+		return time.Time{}, &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Namespace %s is not a clone", namespace),
+		}
+	}
+
+	base := time.Now()
+	if current, err := time.Parse(time.RFC3339, ns.Annotations[TARGET_EXPIRES_AT_ANNOTATION]); err == nil && current.After(base) {
+		base = current
+	}
+	expiry, errObj := parseExpiry(ttl, expiresAt, base)
+	if errObj != nil {
+		return time.Time{}, errObj
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, TARGET_EXPIRES_AT_ANNOTATION, expiry.Format(time.RFC3339))
+	if _, err := clientset.CoreV1().Namespaces().Patch(context.TODO(), namespace, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return time.Time{}, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error extending expiry of namespace %s: %v", namespace, err),
+		}
+	}
+	log.Printf("Namespace %s now expires at %s\n", namespace, expiry.Format(time.RFC3339))
+	return *expiry, nil
+}
@@ -0,0 +1,591 @@
+package managers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// Outcomes of a resync for a single object
+const (
+	ResyncActionCreated       = "Created"
+	ResyncActionUpdated       = "Updated"
+	ResyncActionUnchanged     = "Unchanged"
+	ResyncActionSourceMissing = "SourceMissing"
+	ResyncActionSkipped       = "Skipped"
+)
+
+// Fields recorded in TARGET_OVERRIDES_ANNOTATION
+const replicasOverride = "spec.replicas"
+
+func dataOverride(key string) string {
+	return "data." + key
+}
+
+// containerImageOverride uses the same path as the image mutations of applyImageOverrides
+func containerImageOverride(podSpecPath, container string) string {
+	return fmt.Sprintf("%s.containers[%s].image", podSpecPath, container)
+}
+
+func initContainerImageOverride(podSpecPath, container string) string {
+	return fmt.Sprintf("%s.initContainers[%s].image", podSpecPath, container)
+}
+
+type ResyncChange struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	// Changed fields, e.g. "updated data.LOG_LEVEL". Secret values are never reported.
+	Changes []string `json:"changes,omitempty"`
+	// Locally overridden fields that were kept
+	Preserved []string `json:"preserved,omitempty"`
+	// Why the object was skipped
+	Reason string `json:"reason,omitempty"`
+}
+
+type ResyncReport struct {
+	SourceNamespace string         `json:"sourceNamespace"`
+	TargetNamespace string         `json:"targetNamespace"`
+	SyncedAt        time.Time      `json:"syncedAt"`
+	Objects         []ResyncChange `json:"objects"`
+}
+
+// getOverrides returns the fields recorded as local overrides on a cloned object
+func getOverrides(annotations map[string]string) []string {
+	value, ok := annotations[TARGET_OVERRIDES_ANNOTATION]
+	if !ok || value == "" {
+		return nil
+	}
+	var overrides []string
+	if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+		log.Printf("Ignoring invalid %s annotation %q: %v\n", TARGET_OVERRIDES_ANNOTATION, value, err)
+		return nil
+	}
+	return overrides
+}
+
+func encodeOverrides(overrides []string) string {
+	sort.Strings(overrides)
+	value, _ := json.Marshal(overrides)
+	return string(value)
+}
+
+// overridesPatch returns the JSON patch operation adding fields to the overrides recorded on an object
+func overridesPatch(annotations map[string]string, fields ...string) map[string]interface{} {
+	overrides := getOverrides(annotations)
+	for _, field := range fields {
+		if !slices.Contains(overrides, field) {
+			overrides = append(overrides, field)
+		}
+	}
+	return overridesAnnotationPatch(annotations, overrides)
+}
+
+// removeOverridesPatch returns the JSON patch operation dropping fields from the overrides recorded on an
+// object, so a resync follows the source again
+func removeOverridesPatch(annotations map[string]string, fields ...string) map[string]interface{} {
+	overrides := slices.DeleteFunc(getOverrides(annotations), func(field string) bool {
+		return slices.Contains(fields, field)
+	})
+	return overridesAnnotationPatch(annotations, overrides)
+}
+
+func overridesAnnotationPatch(annotations map[string]string, overrides []string) map[string]interface{} {
+	if overrides == nil {
+		overrides = []string{}
+	}
+	if annotations == nil {
+		return map[string]interface{}{
+			"op":    "add",
+			"path":  "/metadata/annotations",
+			"value": map[string]string{TARGET_OVERRIDES_ANNOTATION: encodeOverrides(overrides)},
+		}
+	}
+	return map[string]interface{}{
+		"op":    "add",
+		"path":  annotationPatchPath(TARGET_OVERRIDES_ANNOTATION),
+		"value": encodeOverrides(overrides),
+	}
+}
+
+// annotationPatchPath is the JSON patch path of an annotation, "/" in the key is escaped as "~1"
+func annotationPatchPath(key string) string {
+	return "/metadata/annotations/" + strings.ReplaceAll(key, "/", "~1")
+}
+
+// mergeData returns the source data with overridden keys kept from the target, along with the changed
+// and preserved keys. Keys only present in the target are removed unless they are overridden.
+func mergeData[V any](source, target map[string]V, overrides []string, equal func(a, b V) bool) (map[string]V, []string, []string) {
+	merged := make(map[string]V)
+	var changes, preserved []string
+	for key, value := range source {
+		if slices.Contains(overrides, dataOverride(key)) {
+			continue
+		}
+		merged[key] = value
+		if targetValue, ok := target[key]; !ok {
+			changes = append(changes, "added "+dataOverride(key))
+		} else if !equal(value, targetValue) {
+			changes = append(changes, "updated "+dataOverride(key))
+		}
+	}
+	for key, value := range target {
+		if slices.Contains(overrides, dataOverride(key)) {
+			merged[key] = value
+			preserved = append(preserved, dataOverride(key))
+		} else if _, ok := source[key]; !ok {
+			changes = append(changes, "removed "+dataOverride(key))
+		}
+	}
+	sort.Strings(changes)
+	sort.Strings(preserved)
+	return merged, changes, preserved
+}
+
+// ResyncNamespace brings an existing clone up to date with its source namespace. ConfigMaps, Secrets,
+// ServiceAccounts and Deployments that were cloned are updated in place, keeping every field recorded
+// as a local override, and objects added to the source since the last clone or resync are cloned.
+func ResyncNamespace(clientset *kubernetes.Clientset, targetNamespace string) (ResyncReport, *Error) {
+	sourceNamespace, errObj := getCloneSource(clientset, targetNamespace)
+	if errObj != nil {
+		return ResyncReport{}, errObj
+	}
+
+	report := ResyncReport{
+		SourceNamespace: sourceNamespace,
+		TargetNamespace: targetNamespace,
+		Objects:         []ResyncChange{},
+	}
+	// The options of the clone decide how Secrets are resynced and how objects added to the source since
+	// the last clone are cloned
+	options, errObj := getCloneOptions(clientset, targetNamespace)
+	if errObj != nil {
+		return report, errObj
+	}
+	resyncs := []func(*kubernetes.Clientset, string, string) ([]ResyncChange, *Error){
+		resyncConfigMaps,
+		resyncServiceAccounts,
+		func(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) ([]ResyncChange, *Error) {
+			return resyncSecrets(clientset, sourceNamespace, targetNamespace, options.Secrets)
+		},
+		resyncDeployments,
+	}
+	for _, resync := range resyncs {
+		changes, errObj := resync(clientset, sourceNamespace, targetNamespace)
+		if errObj != nil {
+			return report, errObj
+		}
+		report.Objects = append(report.Objects, changes...)
+	}
+
+	// Existing objects are skipped, substituted values aren't kept so the Secrets they apply to are too
+	options.Secrets = options.Secrets.forResync()
+	job := &CloneJob{
+		SourceNamespace: sourceNamespace,
+		TargetNamespace: targetNamespace,
+		Options:         options,
+		objects:         []ObjectProgress{},
+	}
+	if options.NameRegex != "" {
+		job.nameRegex = regexp.MustCompile(options.NameRegex)
+	}
+	steps := []cloneStep{
+		{"ConfigMap", func() *Error { return CloneConfigMap(clientset, sourceNamespace, targetNamespace, job) }},
+		{"Secret", func() *Error { return CloneSecret(clientset, sourceNamespace, targetNamespace, job) }},
+		{"ServiceAccount", func() *Error { return CloneSeviceAccount(clientset, sourceNamespace, targetNamespace, job) }},
+		{"Deployment", func() *Error { return CloneDeployments(clientset, sourceNamespace, targetNamespace, job) }},
+	}
+	for _, step := range steps {
+		if !job.includesKind(step.kind) {
+			continue
+		}
+		job.setCurrentKind(step.kind)
+		if errObj := step.clone(); errObj != nil {
+			return report, errObj
+		}
+	}
+	for _, object := range job.Status().Objects {
+		if object.Status == ObjectStatusCreated || object.Status == ObjectStatusReady {
+			report.Objects = append(report.Objects, ResyncChange{Kind: object.Kind, Name: object.Name, Action: ResyncActionCreated})
+		}
+	}
+
+	report.SyncedAt = time.Now().UTC()
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, TARGET_SYNCED_AT_ANNOTATION, report.SyncedAt.Format(time.RFC3339))
+	if _, err := clientset.CoreV1().Namespaces().Patch(context.TODO(), targetNamespace, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		log.Printf("Error recording resync time on namespace %s: %v\n", targetNamespace, err)
+	}
+	log.Printf("Namespace %s resynced from %s\n", targetNamespace, sourceNamespace)
+	return report, nil
+}
+
+// resyncChange turns the changes of one object into a report entry
+func resyncChange(kind, name string, changes, preserved []string) ResyncChange {
+	action := ResyncActionUpdated
+	if len(changes) == 0 {
+		action = ResyncActionUnchanged
+	}
+	return ResyncChange{Kind: kind, Name: name, Action: action, Changes: changes, Preserved: preserved}
+}
+
+func resyncConfigMaps(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) ([]ResyncChange, *Error) {
+	configMaps, err := clientset.CoreV1().ConfigMaps(targetNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	var report []ResyncChange
+	for _, configMap := range configMaps.Items {
+		sourceName, ok := configMap.Annotations[TARGET_CM_ANNOTATION]
+		if !ok {
+			continue
+		}
+		var changes, preserved []string
+		retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			source, err := clientset.CoreV1().ConfigMaps(sourceNamespace).Get(context.TODO(), sourceName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			target, err := clientset.CoreV1().ConfigMaps(targetNamespace).Get(context.TODO(), configMap.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			target.Data, changes, preserved = mergeData(source.Data, target.Data, getOverrides(target.Annotations), func(a, b string) bool { return a == b })
+			if len(changes) == 0 {
+				return nil
+			}
+			_, err = clientset.CoreV1().ConfigMaps(targetNamespace).Update(context.TODO(), target, metav1.UpdateOptions{})
+			return err
+		})
+		if errors.IsNotFound(retryErr) {
+			report = append(report, ResyncChange{Kind: "ConfigMap", Name: configMap.Name, Action: ResyncActionSourceMissing})
+			continue
+		} else if retryErr != nil {
+			return nil, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error resyncing ConfigMap %s: %v", configMap.Name, retryErr),
+			}
+		}
+		report = append(report, resyncChange("ConfigMap", configMap.Name, changes, preserved))
+	}
+	return report, nil
+}
+
+// resyncSecrets updates cloned Secrets under the secret policy of the clone. Keys added to the source are
+// regenerated when the Secret's rule regenerates them, Secrets cloned with an action the policy no longer
+// gives, e.g. by clones made before the policy was kept, are left alone.
+func resyncSecrets(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, policy *SecretPolicy) ([]ResyncChange, *Error) {
+	secrets, err := clientset.CoreV1().Secrets(targetNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	var report []ResyncChange
+	for _, secret := range secrets.Items {
+		sourceName, ok := secret.Annotations[TARGET_SECRET_ANNOTATION]
+		if !ok {
+			continue
+		}
+		var changes, preserved []string
+		var skipReason string
+		var dataErr *Error
+		retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			source, err := clientset.CoreV1().Secrets(sourceNamespace).Get(context.TODO(), sourceName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			target, err := clientset.CoreV1().Secrets(targetNamespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			rule := policy.rule(source)
+			if action, ok := target.Annotations[TARGET_SECRET_ACTION_ANNOTATION]; ok && SecretAction(action) != rule.Action {
+				skipReason = fmt.Sprintf("cloned with secret action %s, the secret policy of the clone gives %s", action, rule.Action)
+				return nil
+			}
+			data, regenerated, errObj := rule.resyncData(source, target.Data)
+			if errObj != nil {
+				dataErr = errObj
+				return nil
+			}
+			overrides := getOverrides(target.Annotations)
+			target.Data, changes, preserved = mergeData(data, target.Data, overrides, bytes.Equal)
+			if len(changes) == 0 {
+				return nil
+			}
+			if len(regenerated) > 0 {
+				// Regenerated keys are kept by the next resyncs like the ones of the clone
+				for _, key := range regenerated {
+					overrides = append(overrides, dataOverride(key))
+				}
+				if target.Annotations == nil {
+					target.Annotations = make(map[string]string)
+				}
+				target.Annotations[TARGET_OVERRIDES_ANNOTATION] = encodeOverrides(overrides)
+			}
+			_, err = clientset.CoreV1().Secrets(targetNamespace).Update(context.TODO(), target, metav1.UpdateOptions{})
+			return err
+		})
+		if errors.IsNotFound(retryErr) {
+			report = append(report, ResyncChange{Kind: "Secret", Name: secret.Name, Action: ResyncActionSourceMissing})
+			continue
+		} else if retryErr != nil {
+			return nil, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error resyncing Secret %s: %v", secret.Name, retryErr),
+			}
+		} else if dataErr != nil {
+			return nil, dataErr
+		} else if skipReason != "" {
+			log.Printf("Not resyncing Secret %s: %s\n", secret.Name, skipReason)
+			report = append(report, ResyncChange{Kind: "Secret", Name: secret.Name, Action: ResyncActionSkipped, Reason: skipReason})
+			continue
+		}
+		report = append(report, resyncChange("Secret", secret.Name, changes, preserved))
+	}
+	return report, nil
+}
+
+func resyncServiceAccounts(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) ([]ResyncChange, *Error) {
+	serviceAccounts, err := clientset.CoreV1().ServiceAccounts(targetNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	var report []ResyncChange
+	for _, serviceAccount := range serviceAccounts.Items {
+		sourceName, ok := serviceAccount.Annotations[TARGET_SA_ANNOTATION]
+		if !ok {
+			continue
+		}
+		var changes, preserved []string
+		retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			source, err := clientset.CoreV1().ServiceAccounts(sourceNamespace).Get(context.TODO(), sourceName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			target, err := clientset.CoreV1().ServiceAccounts(targetNamespace).Get(context.TODO(), serviceAccount.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			changes, preserved = syncServiceAccount(source, target)
+			if len(changes) == 0 {
+				return nil
+			}
+			_, err = clientset.CoreV1().ServiceAccounts(targetNamespace).Update(context.TODO(), target, metav1.UpdateOptions{})
+			return err
+		})
+		if errors.IsNotFound(retryErr) {
+			report = append(report, ResyncChange{Kind: "ServiceAccount", Name: serviceAccount.Name, Action: ResyncActionSourceMissing})
+			continue
+		} else if retryErr != nil {
+			return nil, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error resyncing ServiceAccount %s: %v", serviceAccount.Name, retryErr),
+			}
+		}
+		report = append(report, resyncChange("ServiceAccount", serviceAccount.Name, changes, preserved))
+	}
+	return report, nil
+}
+
+// syncServiceAccount copies the source labels, annotations, pull secrets, secrets and token automount setting
+// onto a cloned ServiceAccount, the cloner's own annotations and overridden fields are kept
+func syncServiceAccount(source, target *v1.ServiceAccount) ([]string, []string) {
+	overrides := getOverrides(target.Annotations)
+	var labelChanges, annotationChanges, preserved, labelsPreserved, annotationsPreserved []string
+	target.Labels, labelChanges, labelsPreserved = syncMetadata("metadata.labels", source.Labels, target.Labels, overrides)
+	target.Annotations, annotationChanges, annotationsPreserved = syncMetadata("metadata.annotations", source.Annotations, target.Annotations, overrides)
+	changes := append(labelChanges, annotationChanges...)
+	preserved = append(labelsPreserved, annotationsPreserved...)
+
+	fields := []struct {
+		name           string
+		source, target interface{}
+		update         func()
+	}{
+		{"imagePullSecrets", source.ImagePullSecrets, target.ImagePullSecrets, func() { target.ImagePullSecrets = source.ImagePullSecrets }},
+		{"secrets", source.Secrets, target.Secrets, func() { target.Secrets = source.Secrets }},
+		{"automountServiceAccountToken", source.AutomountServiceAccountToken, target.AutomountServiceAccountToken, func() {
+			target.AutomountServiceAccountToken = source.AutomountServiceAccountToken
+		}},
+	}
+	for _, field := range fields {
+		if equality.Semantic.DeepEqual(field.source, field.target) {
+			continue
+		}
+		if slices.Contains(overrides, field.name) {
+			preserved = append(preserved, field.name)
+			continue
+		}
+		field.update()
+		changes = append(changes, "updated "+field.name)
+	}
+	sort.Strings(changes)
+	sort.Strings(preserved)
+	return changes, preserved
+}
+
+// syncMetadata merges source labels or annotations into a target's, the cloner's own keys and overridden keys
+// are kept
+func syncMetadata(path string, source, target map[string]string, overrides []string) (map[string]string, []string, []string) {
+	var changes, preserved []string
+	merged := make(map[string]string)
+	for key, value := range target {
+		field := fmt.Sprintf("%s[%s]", path, key)
+		if strings.HasPrefix(key, "cloner.io/") {
+			merged[key] = value
+		} else if slices.Contains(overrides, field) {
+			merged[key] = value
+			preserved = append(preserved, field)
+		} else if _, ok := source[key]; !ok {
+			changes = append(changes, "removed "+field)
+		}
+	}
+	for key, value := range source {
+		field := fmt.Sprintf("%s[%s]", path, key)
+		if strings.HasPrefix(key, "cloner.io/") || slices.Contains(overrides, field) {
+			continue
+		}
+		if targetValue, ok := target[key]; !ok {
+			changes = append(changes, "added "+field)
+		} else if targetValue != value {
+			changes = append(changes, "updated "+field)
+		}
+		merged[key] = value
+	}
+	return merged, changes, preserved
+}
+
+func resyncDeployments(clientset *kubernetes.Clientset, sourceNamespace, targetNamespace string) ([]ResyncChange, *Error) {
+	deployments, err := clientset.AppsV1().Deployments(targetNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	var report []ResyncChange
+	for _, deployment := range deployments.Items {
+		sourceName, ok := deployment.Annotations[TARGET_DEPLOYMENT_ANNOTATION]
+		if !ok {
+			continue
+		}
+		var changes, preserved []string
+		retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			source, err := clientset.AppsV1().Deployments(sourceNamespace).Get(context.TODO(), sourceName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			target, err := clientset.AppsV1().Deployments(targetNamespace).Get(context.TODO(), deployment.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			changes, preserved = syncDeployment(source, target)
+			if len(changes) == 0 {
+				return nil
+			}
+			_, err = clientset.AppsV1().Deployments(targetNamespace).Update(context.TODO(), target, metav1.UpdateOptions{})
+			return err
+		})
+		if errors.IsNotFound(retryErr) {
+			report = append(report, ResyncChange{Kind: "Deployment", Name: deployment.Name, Action: ResyncActionSourceMissing})
+			continue
+		} else if retryErr != nil {
+			return nil, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error resyncing Deployment %s: %v", deployment.Name, retryErr),
+			}
+		}
+		report = append(report, resyncChange("Deployment", deployment.Name, changes, preserved))
+	}
+	return report, nil
+}
+
+// syncDeployment replaces the spec of a cloned Deployment with the source spec. Overridden replicas
+// and container images are kept from the target.
+func syncDeployment(source, target *appsv1.Deployment) ([]string, []string) {
+	var changes, preserved []string
+	overrides := getOverrides(target.Annotations)
+	spec := source.Spec.DeepCopy()
+	podSpecPath := "spec.template.spec"
+
+	if slices.Contains(overrides, replicasOverride) {
+		spec.Replicas = target.Spec.Replicas
+		preserved = append(preserved, replicasOverride)
+	} else if !equality.Semantic.DeepEqual(spec.Replicas, target.Spec.Replicas) {
+		changes = append(changes, "updated "+replicasOverride)
+	}
+
+	syncImages := func(containers, targetContainers []v1.Container, override func(string, string) string) {
+		for i, container := range containers {
+			field := override(podSpecPath, container.Name)
+			for _, targetContainer := range targetContainers {
+				if targetContainer.Name != container.Name {
+					continue
+				}
+				if slices.Contains(overrides, field) {
+					containers[i].Image = targetContainer.Image
+					preserved = append(preserved, field)
+				} else if targetContainer.Image != container.Image {
+					changes = append(changes, "updated "+field)
+				}
+			}
+		}
+	}
+	syncImages(spec.Template.Spec.InitContainers, target.Spec.Template.Spec.InitContainers, initContainerImageOverride)
+	syncImages(spec.Template.Spec.Containers, target.Spec.Template.Spec.Containers, containerImageOverride)
+
+	// Report the remaining differences at the pod template level, images are already reported above
+	template := spec.Template.DeepCopy()
+	for i := range template.Spec.InitContainers {
+		template.Spec.InitContainers[i].Image = ""
+	}
+	for i := range template.Spec.Containers {
+		template.Spec.Containers[i].Image = ""
+	}
+	targetTemplate := target.Spec.Template.DeepCopy()
+	for i := range targetTemplate.Spec.InitContainers {
+		targetTemplate.Spec.InitContainers[i].Image = ""
+	}
+	for i := range targetTemplate.Spec.Containers {
+		targetTemplate.Spec.Containers[i].Image = ""
+	}
+	if !equality.Semantic.DeepEqual(template, targetTemplate) {
+		changes = append(changes, "updated spec.template")
+	}
+	rest, targetRest := spec.DeepCopy(), target.Spec.DeepCopy()
+	rest.Replicas, rest.Template = nil, v1.PodTemplateSpec{}
+	targetRest.Replicas, targetRest.Template = nil, v1.PodTemplateSpec{}
+	if !equality.Semantic.DeepEqual(rest, targetRest) {
+		changes = append(changes, "updated spec")
+	}
+
+	target.Spec = *spec
+	sort.Strings(changes)
+	sort.Strings(preserved)
+	return changes, preserved
+}
@@ -0,0 +1,54 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+// rollbackClonedObjects undoes a failed clone into a namespace that existed before the job. Only the objects
+// the job created are deleted, newest first, everything else in the namespace is left alone.
+func rollbackClonedObjects(dynamicClient dynamic.Interface, targetNamespace string, job *CloneJob) *Error {
+	objects := job.Status().Objects
+	propagation := metav1.DeletePropagationBackground
+	for i := len(objects) - 1; i >= 0; i-- {
+		object := objects[i]
+		if object.Kind == "Namespace" || !object.created {
+			continue
+		}
+		if object.Kind == "HeaderRoute" {
+			// Header routes live in a source VirtualService, recorded as <namespace>/<name>
+			sourceNamespace, name, _ := strings.Cut(object.Name, "/")
+			err := updateSourceRoutes(dynamicClient, sourceNamespace, name, func(routes []interface{}) []interface{} {
+				return withoutCloneRoutes(routes, targetNamespace)
+			})
+			if err != nil && !errors.IsNotFound(err) {
+				return &Error{
+					Code:    http.StatusInternalServerError,
+					Message: fmt.Sprintf("Error removing routes to %s from VirtualService %s: %v", targetNamespace, object.Name, err),
+				}
+			}
+			log.Printf("Removed routes to %s from VirtualService %s\n", targetNamespace, object.Name)
+			continue
+		}
+		if object.resource.Empty() {
+			log.Printf("Not rolling back %s %s, its resource is unknown\n", object.Kind, object.Name)
+			continue
+		}
+		err := dynamicClient.Resource(object.resource).Namespace(targetNamespace).Delete(context.TODO(), object.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !errors.IsNotFound(err) {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error deleting %s %s from %s: %v", object.Kind, object.Name, targetNamespace, err),
+			}
+		}
+		log.Printf("Rolled back %s %s in %s\n", object.Kind, object.Name, targetNamespace)
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package managers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// Kinds that can be scaled
+const (
+	ScaleKindDeployment  = "Deployment"
+	ScaleKindStatefulSet = "StatefulSet"
+)
+
+// WorkloadScale is the replica change of a single workload
+type WorkloadScale struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	From int32  `json:"from"`
+	To   int32  `json:"to"`
+}
+
+// scaleTarget is what scaling needs to know about a Deployment or StatefulSet
+type scaleTarget struct {
+	annotations map[string]string
+	replicas    int32
+}
+
+// getScaleTarget reads a workload, when guard is set it must be a Deployment or StatefulSet cloned by this system
+func getScaleTarget(clientset *kubernetes.Clientset, namespace, kind, name string, guard bool) (scaleTarget, *Error) {
+	var target scaleTarget
+	var err error
+	switch kind {
+	case ScaleKindDeployment:
+		deployment, getErr := clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			// Only allow scaling deployments that are cloned by this system using the annotations set
+			if guard {
+				if errObj := validateDeploymentEliblity(clientset, deployment); errObj != nil {
+					return target, errObj
+				}
+			}
+			target = scaleTarget{annotations: deployment.Annotations, replicas: 1}
+			if deployment.Spec.Replicas != nil {
+				target.replicas = *deployment.Spec.Replicas
+			}
+		}
+	case ScaleKindStatefulSet:
+		statefulSet, getErr := clientset.AppsV1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			if guard {
+				if errObj := validateStatefulSetEliblity(statefulSet); errObj != nil {
+					return target, errObj
+				}
+			}
+			target = scaleTarget{annotations: statefulSet.Annotations, replicas: 1}
+			if statefulSet.Spec.Replicas != nil {
+				target.replicas = *statefulSet.Spec.Replicas
+			}
+		}
+	default:
+		return target, &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("%s can't be scaled", kind),
+		}
+	}
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return target, &Error{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("%s %s not found in namespace %s", kind, name, namespace),
+			}
+		}
+		return target, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	return target, nil
+}
+
+// setReplicas patches the replicas of a workload. An explicit count is recorded as a local override so a
+// resync keeps it, following the source removes the override again.
+func setReplicas(clientset *kubernetes.Clientset, namespace, kind, name string, target scaleTarget, replicas int32, followSource bool) *Error {
+	overrides := overridesPatch(target.annotations, replicasOverride)
+	if followSource {
+		overrides = removeOverridesPatch(target.annotations, replicasOverride)
+	}
+	patch := []map[string]interface{}{
+		{
+			"op":    "add",
+			"path":  "/spec/replicas",
+			"value": replicas,
+		},
+		overrides,
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		if kind == ScaleKindDeployment {
+			_, err = clientset.AppsV1().Deployments(namespace).Patch(context.TODO(), name, types.JSONPatchType, patchBytes, metav1.PatchOptions{})
+		} else {
+			_, err = clientset.AppsV1().StatefulSets(namespace).Patch(context.TODO(), name, types.JSONPatchType, patchBytes, metav1.PatchOptions{})
+		}
+		return err
+	})
+	if retryErr != nil {
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: retryErr.Error(),
+		}
+	}
+	log.Printf("%s %s in %s scaled to %d\n", kind, name, namespace, replicas)
+	return nil
+}
+
+// ScaleWorkload scales a Deployment or StatefulSet in a cloned namespace by delta replicas, or to
+// replicas when set. Replicas never go below zero.
+func ScaleWorkload(clientset *kubernetes.Clientset, namespace, kind, name string, delta int32, replicas *int32) (WorkloadScale, *Error) {
+	if errObj := validateClonedNamespace(clientset, namespace); errObj != nil {
+		return WorkloadScale{}, errObj
+	}
+	target, errObj := getScaleTarget(clientset, namespace, kind, name, true)
+	if errObj != nil {
+		return WorkloadScale{}, errObj
+	}
+	to := target.replicas + delta
+	if replicas != nil {
+		to = *replicas
+	}
+	if to < 0 {
+		to = 0
+	}
+	scale := WorkloadScale{Kind: kind, Name: name, From: target.replicas, To: to}
+	if to == target.replicas {
+		return scale, nil
+	}
+	return scale, setReplicas(clientset, namespace, kind, name, target, to, false)
+}
+
+// ScaleNamespace sets every Deployment and StatefulSet of a cloned namespace to replicas, or back to the
+// replica count of its source workload when restore is set
+func ScaleNamespace(clientset *kubernetes.Clientset, namespace string, replicas *int32, restore bool) ([]WorkloadScale, *Error) {
+	if (replicas == nil && !restore) || (replicas != nil && restore) {
+		return nil, &Error{
+			Code:    http.StatusBadRequest,
+			Message: "One of replicas and restore is required",
+		}
+	}
+	if replicas != nil && *replicas < 0 {
+		return nil, &Error{
+			Code:    http.StatusBadRequest,
+			Message: "Replicas can't be negative",
+		}
+	}
+	ns, err := clientset.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	if ns.Annotations[TARGET_NS_ANNOTATION_ENABLED] != "true" {
+		return nil, &Error{
+			Code:    errorCodes["NamespaceNotCloned"],
+			Message: fmt.Sprintf("Namespace %s was not created by the cloner", namespace),
+		}
+	}
+	sourceNamespace := ns.Annotations[TARGET_NS_ANNOTATION]
+
+	// Workloads of the clone, with the name of their source workload
+	type workload struct {
+		kind, name, sourceName string
+	}
+	var workloads []workload
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	for _, deployment := range deployments.Items {
+		workloads = append(workloads, workload{ScaleKindDeployment, deployment.Name, deployment.Annotations[TARGET_DEPLOYMENT_ANNOTATION]})
+	}
+	statefulSets, err := clientset.AppsV1().StatefulSets(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	for _, statefulSet := range statefulSets.Items {
+		workloads = append(workloads, workload{ScaleKindStatefulSet, statefulSet.Name, statefulSet.Annotations[TARGET_STS_ANNOTATION]})
+	}
+
+	scales := []WorkloadScale{}
+	for _, w := range workloads {
+		target, errObj := getScaleTarget(clientset, namespace, w.kind, w.name, true)
+		if errObj != nil {
+			// Workloads not created by the cloner are left alone
+			log.Printf("Not scaling %s %s: %s\n", w.kind, w.name, errObj.Message)
+			continue
+		}
+		to := int32(0)
+		if replicas != nil {
+			to = *replicas
+		} else {
+			if w.sourceName == "" || sourceNamespace == "" {
+				log.Printf("Not restoring %s %s, it has no source workload\n", w.kind, w.name)
+				continue
+			}
+			source, errObj := getScaleTarget(clientset, sourceNamespace, w.kind, w.sourceName, false)
+			if errObj != nil {
+				if errObj.Code == http.StatusNotFound {
+					log.Printf("Not restoring %s %s, source %s not found in %s\n", w.kind, w.name, w.sourceName, sourceNamespace)
+					continue
+				}
+				return scales, errObj
+			}
+			to = source.replicas
+		}
+		if errObj := setReplicas(clientset, namespace, w.kind, w.name, target, to, restore); errObj != nil {
+			return scales, errObj
+		}
+		scales = append(scales, WorkloadScale{Kind: w.kind, Name: w.name, From: target.replicas, To: to})
+	}
+	return scales, nil
+}
+
+// applyZeroReplicas returns the replicas of a workload cloned with zero replicas. The source count is kept in
+// TARGET_ORIGINAL_REPLICAS_ANNOTATION for WakeNamespace and the replicas are recorded as a local override.
+func applyZeroReplicas(replicas *int32, annotations map[string]string, mutations map[string]interface{}) *int32 {
+	original := int32(1)
+	if replicas != nil {
+		original = *replicas
+	}
+	annotations[TARGET_ORIGINAL_REPLICAS_ANNOTATION] = strconv.Itoa(int(original))
+	overrides := getOverrides(annotations)
+	if !slices.Contains(overrides, replicasOverride) {
+		overrides = append(overrides, replicasOverride)
+	}
+	annotations[TARGET_OVERRIDES_ANNOTATION] = encodeOverrides(overrides)
+	mutations[replicasOverride] = 0
+	zero := int32(0)
+	return &zero
+}
+
+// WakeNamespace restores the source replica count of workloads cloned with zero replicas. Only the named
+// workloads are woken when names is not empty.
+func WakeNamespace(clientset *kubernetes.Clientset, namespace string, names []string) ([]WorkloadScale, *Error) {
+	if errObj := validateClonedNamespace(clientset, namespace); errObj != nil {
+		return nil, errObj
+	}
+	type workload struct {
+		kind, name  string
+		annotations map[string]string
+		replicas    int32
+	}
+	var workloads []workload
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	for _, deployment := range deployments.Items {
+		if deployment.Spec.Replicas != nil {
+			workloads = append(workloads, workload{ScaleKindDeployment, deployment.Name, deployment.Annotations, *deployment.Spec.Replicas})
+		}
+	}
+	statefulSets, err := clientset.AppsV1().StatefulSets(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	for _, statefulSet := range statefulSets.Items {
+		if statefulSet.Spec.Replicas != nil {
+			workloads = append(workloads, workload{ScaleKindStatefulSet, statefulSet.Name, statefulSet.Annotations, *statefulSet.Spec.Replicas})
+		}
+	}
+
+	scales := []WorkloadScale{}
+	for _, w := range workloads {
+		value, ok := w.annotations[TARGET_ORIGINAL_REPLICAS_ANNOTATION]
+		if !ok || (len(names) > 0 && !slices.Contains(names, w.name)) {
+			continue
+		}
+		original, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || original < 0 {
+			log.Printf("Not waking %s %s, invalid %s annotation %q\n", w.kind, w.name, TARGET_ORIGINAL_REPLICAS_ANNOTATION, value)
+			continue
+		}
+		// Restore the replicas, drop the annotation and follow the source replicas again on a resync
+		patch := []map[string]interface{}{
+			{
+				"op":    "add",
+				"path":  "/spec/replicas",
+				"value": original,
+			},
+			{
+				"op":   "remove",
+				"path": annotationPatchPath(TARGET_ORIGINAL_REPLICAS_ANNOTATION),
+			},
+			removeOverridesPatch(w.annotations, replicasOverride),
+		}
+		patchBytes, err := json.Marshal(patch)
+		if err != nil {
+			return scales, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			}
+		}
+		if w.kind == ScaleKindDeployment {
+			_, err = clientset.AppsV1().Deployments(namespace).Patch(context.TODO(), w.name, types.JSONPatchType, patchBytes, metav1.PatchOptions{})
+		} else {
+			_, err = clientset.AppsV1().StatefulSets(namespace).Patch(context.TODO(), w.name, types.JSONPatchType, patchBytes, metav1.PatchOptions{})
+		}
+		if err != nil {
+			return scales, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error waking %s %s: %v", w.kind, w.name, err),
+			}
+		}
+		log.Printf("%s %s in %s woken up with %d replicas\n", w.kind, w.name, namespace, original)
+		scales = append(scales, WorkloadScale{Kind: w.kind, Name: w.name, From: w.replicas, To: int32(original)})
+	}
+	return scales, nil
+}
@@ -0,0 +1,302 @@
+package managers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// SecretAction is what a secret policy does with a matching Secret
+type SecretAction string
+
+const (
+	// Copy the Secret as it is, the action for secrets no rule matches
+	SecretActionCopy SecretAction = "copy"
+	// Leave the Secret out of the clone
+	SecretActionSkip SecretAction = "skip"
+	// Replace values with random values of the same length
+	SecretActionRegenerate SecretAction = "regenerate"
+	// Replace values with the ones given in the clone request
+	SecretActionSubstitute SecretAction = "substitute"
+)
+
+// SecretRule applies an action to the Secrets matching all of its type, name and owner
+type SecretRule struct {
+	// Secret type, e.g. "kubernetes.io/tls". Every type when empty.
+	Type v1.SecretType `json:"type"`
+	// Regular expression the whole Secret name must match. Every name when empty.
+	Name string `json:"name"`
+	// Owner of the Secret as "apiVersion/kind", e.g. "kube-green.com/v1alpha1/SleepInfo"
+	Owner  string       `json:"owner"`
+	Action SecretAction `json:"action"`
+	// Keys regenerated or substituted, every key when empty
+	Keys []string `json:"keys"`
+	// Why secrets are skipped, shown in the clone report
+	Reason string `json:"reason"`
+
+	// Name compiled by validate
+	name *regexp.Regexp
+}
+
+// SecretPolicy decides per Secret whether it is copied, skipped, regenerated or substituted
+type SecretPolicy struct {
+	// Rules checked in order after MandatorySecretRules and before DefaultSecretRules, the first matching rule
+	// applies. Secrets no rule matches are copied.
+	Rules []SecretRule `json:"rules"`
+	// Values of substituted keys, by Secret name and key
+	Values map[string]map[string]string `json:"values"`
+}
+
+// SecretOutcome is what happened to a Secret of the source namespace, values are never reported
+type SecretOutcome struct {
+	Name   string        `json:"name"`
+	Type   v1.SecretType `json:"type,omitempty"`
+	Action SecretAction  `json:"action"`
+	// Regenerated or substituted keys
+	Keys   []string `json:"keys,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+// Rules applied to every clone before the rules of the request, which can't override them
+var MandatorySecretRules = []SecretRule{
+	// Legacy tokens are bound to the source ServiceAccount, they are regenerated along with the cloned one
+	{Type: v1.SecretTypeServiceAccountToken, Action: SecretActionSkip, Reason: "service account token, regenerated with its ServiceAccount"},
+	{Owner: KUBE_GREEN_API_VERSION + "/" + KUBE_GREEN_KIND, Action: SecretActionSkip, Reason: "secret is managed by kube-green"},
+}
+
+// Rules applied to every clone after the rules of the request
+var DefaultSecretRules = []SecretRule{
+	{Name: `sh\.helm\.release.*`, Action: SecretActionSkip, Reason: "Helm release secret"},
+	{Type: "helm.sh/release.v1", Action: SecretActionSkip, Reason: "Helm release secret"},
+}
+
+func init() {
+	for _, rules := range [][]SecretRule{MandatorySecretRules, DefaultSecretRules} {
+		for i := range rules {
+			if errObj := rules[i].validate(); errObj != nil {
+				panic(errObj.Message)
+			}
+		}
+	}
+}
+
+// Characters of regenerated values
+const regeneratedValueChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func (r *SecretRule) validate() *Error {
+	switch r.Action {
+	case SecretActionCopy, SecretActionSkip, SecretActionRegenerate, SecretActionSubstitute:
+	default:
+		return &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Invalid secret action %q, expected one of copy, skip, regenerate or substitute", r.Action),
+		}
+	}
+	name, err := compilePattern(r.Name)
+	if err != nil {
+		return &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Invalid secret name pattern %q: %v", r.Name, err),
+		}
+	}
+	r.name = name
+	if r.Owner != "" && !strings.Contains(r.Owner, "/") {
+		return &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Invalid secret owner %q, expected apiVersion/kind", r.Owner),
+		}
+	}
+	return nil
+}
+
+func (p *SecretPolicy) validate() *Error {
+	if p == nil {
+		return nil
+	}
+	for i := range p.Rules {
+		if errObj := p.Rules[i].validate(); errObj != nil {
+			return errObj
+		}
+	}
+	return nil
+}
+
+func (r SecretRule) matches(secret *v1.Secret) bool {
+	if r.Type != "" && r.Type != secret.Type {
+		return false
+	}
+	if r.Name != "" && !r.name.MatchString(secret.Name) {
+		return false
+	}
+	if r.Owner != "" {
+		owned := false
+		for _, ref := range secret.OwnerReferences {
+			if ref.APIVersion+"/"+ref.Kind == r.Owner {
+				owned = true
+			}
+		}
+		if !owned {
+			return false
+		}
+	}
+	return true
+}
+
+// rule returns the first of MandatorySecretRules, the policy's rules and DefaultSecretRules matching a Secret
+func (p *SecretPolicy) rule(secret *v1.Secret) SecretRule {
+	rules := append([]SecretRule(nil), MandatorySecretRules...)
+	if p != nil {
+		rules = append(rules, p.Rules...)
+	}
+	for _, rule := range append(rules, DefaultSecretRules...) {
+		if rule.matches(secret) {
+			return rule
+		}
+	}
+	return SecretRule{Action: SecretActionCopy}
+}
+
+// data returns the data of a cloned Secret under rule, along with the regenerated or substituted keys
+func (p *SecretPolicy) data(rule SecretRule, secret *v1.Secret) (map[string][]byte, []string, *Error) {
+	data := make(map[string][]byte, len(secret.Data))
+	for key, value := range secret.Data {
+		data[key] = value
+	}
+	var keys []string
+	switch rule.Action {
+	case SecretActionRegenerate:
+		keys = rule.Keys
+		if len(keys) == 0 {
+			for key := range secret.Data {
+				keys = append(keys, key)
+			}
+		}
+		var regenerated []string
+		for _, key := range keys {
+			value, ok := secret.Data[key]
+			if !ok {
+				continue
+			}
+			random, err := randomValue(len(value))
+			if err != nil {
+				return nil, nil, &Error{
+					Code:    http.StatusInternalServerError,
+					Message: fmt.Sprintf("Error regenerating %s of Secret %s: %v", key, secret.Name, err),
+				}
+			}
+			data[key] = random
+			regenerated = append(regenerated, key)
+		}
+		keys = regenerated
+	case SecretActionSubstitute:
+		var values map[string]string
+		if p != nil {
+			values = p.Values[secret.Name]
+		}
+		keys = rule.Keys
+		if len(keys) == 0 {
+			for key := range values {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return nil, nil, &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("No values to substitute in Secret %s", secret.Name),
+			}
+		}
+		for _, key := range keys {
+			value, ok := values[key]
+			if !ok {
+				return nil, nil, &Error{
+					Code:    http.StatusBadRequest,
+					Message: fmt.Sprintf("No value to substitute for %s of Secret %s", key, secret.Name),
+				}
+			}
+			data[key] = []byte(value)
+		}
+	}
+	sort.Strings(keys)
+	return data, keys, nil
+}
+
+// withoutValues returns the policy kept on a clone, its rules without the substituted values
+func (p *SecretPolicy) withoutValues() *SecretPolicy {
+	if p == nil {
+		return nil
+	}
+	return &SecretPolicy{Rules: p.Rules}
+}
+
+// forResync returns the policy cloning the Secrets added to the source of a clone. Substituted values are
+// not kept on the clone, the Secrets they apply to are skipped.
+func (p *SecretPolicy) forResync() *SecretPolicy {
+	if p == nil {
+		return nil
+	}
+	policy := &SecretPolicy{Rules: make([]SecretRule, len(p.Rules))}
+	for i, rule := range p.Rules {
+		if rule.Action == SecretActionSubstitute {
+			rule.Action = SecretActionSkip
+			rule.Reason = "values to substitute are only given with a clone request"
+		}
+		policy.Rules[i] = rule
+	}
+	return policy
+}
+
+// resyncData returns the source data a resync copies to a Secret cloned under rule. Keys the rule regenerates
+// that the clone doesn't have yet get random values, they are returned to be recorded as overrides.
+func (r SecretRule) resyncData(source *v1.Secret, target map[string][]byte) (map[string][]byte, []string, *Error) {
+	if r.Action != SecretActionRegenerate {
+		return source.Data, nil, nil
+	}
+	data := make(map[string][]byte, len(source.Data))
+	var keys []string
+	for key, value := range source.Data {
+		data[key] = value
+		if _, ok := target[key]; ok || (len(r.Keys) > 0 && !slices.Contains(r.Keys, key)) {
+			continue
+		}
+		random, err := randomValue(len(value))
+		if err != nil {
+			return nil, nil, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error regenerating %s of Secret %s: %v", key, source.Name, err),
+			}
+		}
+		data[key] = random
+		keys = append(keys, key)
+	}
+	return data, keys, nil
+}
+
+// randomValue returns length random characters from regeneratedValueChars
+func randomValue(length int) ([]byte, error) {
+	value := make([]byte, length)
+	limit := big.NewInt(int64(len(regeneratedValueChars)))
+	for i := range value {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return nil, err
+		}
+		value[i] = regeneratedValueChars[n.Int64()]
+	}
+	return value, nil
+}
+
+// secretSkipReason tells why the built in rules never clone a Secret, empty when they do
+func secretSkipReason(secret *v1.Secret) string {
+	rule := (*SecretPolicy)(nil).rule(secret)
+	if rule.Action != SecretActionSkip {
+		return ""
+	}
+	return rule.Reason
+}
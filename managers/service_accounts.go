@@ -0,0 +1,85 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// clonePullSecrets clones the imagePullSecrets of a ServiceAccount that the Secret step left out because of the
+// job's filters, so pods running as the cloned ServiceAccount can pull their images
+func clonePullSecrets(clientset, targetClientset *kubernetes.Clientset, serviceAccount *v1.ServiceAccount, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	for _, ref := range serviceAccount.ImagePullSecrets {
+		secret, err := clientset.CoreV1().Secrets(sourceNamespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				log.Printf("Pull secret %s of ServiceAccount %s does not exist in %s\n", ref.Name, serviceAccount.Name, sourceNamespace)
+				continue
+			}
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error getting pull secret %s of ServiceAccount %s: %v", ref.Name, serviceAccount.Name, err),
+			}
+		}
+		if job.includesKind("Secret") && job.selects(secret) {
+			// Already cloned by the Secret step
+			continue
+		}
+		if errObj := cloneSecret(targetClientset, secret, sourceNamespace, targetNamespace, job); errObj != nil {
+			return errObj
+		}
+	}
+	return nil
+}
+
+// regenerateToken creates an empty legacy token Secret for a cloned ServiceAccount under the name of the source
+// token. The token controller issues a new token for the cloned ServiceAccount into it.
+func regenerateToken(targetClientset *kubernetes.Clientset, token *v1.Secret, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	_, err := targetClientset.CoreV1().Secrets(targetNamespace).Get(context.TODO(), token.Name, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error checking for existing Secret %s: %v", token.Name, err),
+		}
+	} else if err == nil {
+		log.Printf("Secret %s already exists in %s, skipping creation\n", token.Name, targetNamespace)
+		job.skip("Secret", token.Name, "already exists")
+		return nil
+	}
+
+	annotations := make(map[string]string)
+	annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+	annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+	annotations[TARGET_SA_ANNOTATION] = token.Annotations[v1.ServiceAccountNameKey]
+	annotations[v1.ServiceAccountNameKey] = token.Annotations[v1.ServiceAccountNameKey]
+	newToken := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        token.Name,
+			Namespace:   targetNamespace,
+			Annotations: annotations,
+		},
+		Type: v1.SecretTypeServiceAccountToken,
+	}
+	job.recordSecret(SecretOutcome{
+		Name:   token.Name,
+		Type:   token.Type,
+		Action: SecretActionRegenerate,
+		Reason: "token issued for the cloned ServiceAccount",
+	})
+	created, errObj := job.create("Secret", token.Name, annotations, nil, func(opts metav1.CreateOptions) error {
+		_, err := targetClientset.CoreV1().Secrets(targetNamespace).Create(context.TODO(), newToken, opts)
+		return err
+	})
+	if errObj != nil || !created {
+		return errObj
+	}
+	log.Printf("Token Secret %s regenerated for ServiceAccount %s in %s\n", token.Name, token.Annotations[v1.ServiceAccountNameKey], targetNamespace)
+	job.record("Secret", token.Name, ObjectStatusReady, "token regenerated")
+	return nil
+}
@@ -0,0 +1,107 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// StatefulSetOverrides change cloned StatefulSets and the claims made from their volumeClaimTemplates
+type StatefulSetOverrides struct {
+	// Replicas of every cloned StatefulSet, the source count when not set
+	Replicas *int32 `json:"replicas"`
+	// StorageClass of the volumeClaimTemplates and of the empty claims cloned for them
+	StorageClass string `json:"storageClass"`
+}
+
+func (o *StatefulSetOverrides) validate() *Error {
+	if o == nil {
+		return nil
+	}
+	if o.Replicas != nil && *o.Replicas < 0 {
+		return &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Invalid statefulSets replicas %d", *o.Replicas),
+		}
+	}
+	return nil
+}
+
+// cleanClaimTemplates copies volumeClaimTemplates without their server populated metadata and status,
+// applying the StorageClass override
+func cleanClaimTemplates(templates []v1.PersistentVolumeClaim, overrides *StatefulSetOverrides, mutations map[string]interface{}) []v1.PersistentVolumeClaim {
+	cleaned := make([]v1.PersistentVolumeClaim, len(templates))
+	for i, template := range templates {
+		cleaned[i] = v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        template.Name,
+				Labels:      template.Labels,
+				Annotations: template.Annotations,
+			},
+			Spec: *template.Spec.DeepCopy(),
+		}
+		if overrides != nil && overrides.StorageClass != "" {
+			storageClass := overrides.StorageClass
+			cleaned[i].Spec.StorageClassName = &storageClass
+			mutations[fmt.Sprintf("spec.volumeClaimTemplates[%d].spec.storageClassName", i)] = storageClass
+		}
+	}
+	return cleaned
+}
+
+// Ordinal suffix of the claims a StatefulSet makes from its templates, "<template>-<statefulset>-<ordinal>"
+var claimOrdinal = regexp.MustCompile(`^[0-9]+$`)
+
+// isStatefulSetClaim tells whether a claim was made from the volumeClaimTemplates of one of statefulSets
+func isStatefulSetClaim(claim string, statefulSets []appsv1.StatefulSet) bool {
+	for _, statefulSet := range statefulSets {
+		for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+			prefix := template.Name + "-" + statefulSet.Name + "-"
+			if strings.HasPrefix(claim, prefix) && claimOrdinal.MatchString(strings.TrimPrefix(claim, prefix)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateServiceName checks that the governing service of a StatefulSet exists as a headless service in the
+// target namespace. A dry run creates no services, the source of a planned one is checked instead. The reason
+// is returned when it doesn't exist, e.g. because the clone's filters left it out.
+func validateServiceName(clientset, targetClientset *kubernetes.Clientset, sourceNamespace, targetNamespace string, statefulSet *appsv1.StatefulSet, job *CloneJob) (string, *Error) {
+	name := statefulSet.Spec.ServiceName
+	if name == "" {
+		return "", nil
+	}
+	serviceClientset, serviceNamespace := targetClientset, targetNamespace
+	if job.dryRun() && job.plans("Service", name) {
+		serviceClientset, serviceNamespace = clientset, sourceNamespace
+	}
+	service, err := serviceClientset.CoreV1().Services(serviceNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error checking for Service %s of StatefulSet %s: %v", name, statefulSet.Name, err),
+		}
+	} else if err != nil {
+		if !job.includesKind("Service") {
+			return fmt.Sprintf("serviceName %s is left out by the kind filters", name), nil
+		}
+		if source, err := clientset.CoreV1().Services(sourceNamespace).Get(context.TODO(), name, metav1.GetOptions{}); err == nil && !job.selects(source) {
+			return fmt.Sprintf("serviceName %s is left out by the labelSelector or nameRegex filters", name), nil
+		}
+		return fmt.Sprintf("serviceName %s is not cloned to %s", name, targetNamespace), nil
+	}
+	if service.Spec.ClusterIP != v1.ClusterIPNone {
+		return fmt.Sprintf("serviceName %s is not a headless service", name), nil
+	}
+	return "", nil
+}
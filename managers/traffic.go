@@ -0,0 +1,458 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	DefaultRoutingHeader = "x-clone"
+	// Routes the cloner adds to source VirtualServices are named "cloner:<target>:<route>"
+	CLONE_ROUTE_PREFIX = "cloner:"
+)
+
+var headerNamePattern = regexp.MustCompile(`^[a-z0-9!#$%&'*+.^_|~-]+$`)
+
+// HeaderRouting sends requests carrying a header to the clone. Matching routes are added in front of the
+// routes of the source VirtualServices, every other request still goes to the source.
+type HeaderRouting struct {
+	// Header to match, "x-clone" by default
+	Header string `json:"header"`
+	// Exact header value to match, the target namespace by default
+	Value string `json:"value"`
+}
+
+func (r *HeaderRouting) validate() *Error {
+	if r == nil || r.Header == "" || headerNamePattern.MatchString(r.Header) {
+		return nil
+	}
+	return &Error{
+		Code:    http.StatusBadRequest,
+		Message: fmt.Sprintf("Invalid routing header %q, Istio only matches lower case header names", r.Header),
+	}
+}
+
+func (r *HeaderRouting) header() string {
+	if r.Header == "" {
+		return DefaultRoutingHeader
+	}
+	return r.Header
+}
+
+func (r *HeaderRouting) value(targetNamespace string) string {
+	if r.Value == "" {
+		return targetNamespace
+	}
+	return r.Value
+}
+
+func cloneRouteName(targetNamespace, name string) string {
+	return CLONE_ROUTE_PREFIX + targetNamespace + ":" + name
+}
+
+// isCloneRoute tells whether a route was added by the cloner for targetNamespace, or for any clone when
+// targetNamespace is empty
+func isCloneRoute(route interface{}, targetNamespace string) bool {
+	m, ok := route.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	name, _ := m["name"].(string)
+	if targetNamespace == "" {
+		return strings.HasPrefix(name, CLONE_ROUTE_PREFIX)
+	}
+	return strings.HasPrefix(name, cloneRouteName(targetNamespace, ""))
+}
+
+// cloneDestinationHost returns the fully qualified host of the cloned service for a destination host of a
+// source VirtualService. ok is false for hosts that aren't services of the source namespace.
+func cloneDestinationHost(host, sourceNamespace, targetNamespace string) (string, bool) {
+	labels := strings.Split(host, ".")
+	if len(labels) == 1 {
+		// Short names resolve in the namespace of the VirtualService
+		return host + "." + targetNamespace + ".svc.cluster.local", true
+	}
+	if _, ok := rewriteServiceHost(host, sourceNamespace, targetNamespace); !ok {
+		return host, false
+	}
+	domain := "cluster.local"
+	if len(labels) > 3 {
+		domain = strings.Join(labels[3:], ".")
+	}
+	return labels[0] + "." + targetNamespace + ".svc." + domain, true
+}
+
+// headerRoutes builds the routes sending matching requests to the clone, one for every source route whose
+// destinations include a service of the source namespace
+func headerRoutes(routes []interface{}, routing *HeaderRouting, sourceNamespace, targetNamespace string) []interface{} {
+	var cloneRoutes []interface{}
+	headerMatch := map[string]interface{}{
+		routing.header(): map[string]interface{}{"exact": routing.value(targetNamespace)},
+	}
+	for i, route := range routes {
+		if isCloneRoute(route, "") {
+			continue
+		}
+		source, ok := route.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		cloneRoute := runtime.DeepCopyJSONValue(source).(map[string]interface{})
+		destinations, _, _ := unstructured.NestedSlice(cloneRoute, "route")
+		rewritten := false
+		for _, destination := range destinations {
+			d, ok := destination.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			host, _, _ := unstructured.NestedString(d, "destination", "host")
+			if cloneHost, ok := cloneDestinationHost(host, sourceNamespace, targetNamespace); ok {
+				_ = unstructured.SetNestedField(d, cloneHost, "destination", "host")
+				rewritten = true
+			}
+		}
+		// Redirects, direct responses and routes to other namespaces have nothing to send to the clone
+		if !rewritten {
+			continue
+		}
+		cloneRoute["route"] = destinations
+
+		name, _ := source["name"].(string)
+		if name == "" {
+			name = fmt.Sprintf("%d", i)
+		}
+		cloneRoute["name"] = cloneRouteName(targetNamespace, name)
+
+		matches, _, _ := unstructured.NestedSlice(cloneRoute, "match")
+		if len(matches) == 0 {
+			matches = []interface{}{map[string]interface{}{}}
+		}
+		for _, match := range matches {
+			m, ok := match.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			headers, _, _ := unstructured.NestedMap(m, "headers")
+			if headers == nil {
+				headers = make(map[string]interface{})
+			}
+			for header, value := range headerMatch {
+				headers[header] = runtime.DeepCopyJSONValue(value)
+			}
+			m["headers"] = headers
+		}
+		cloneRoute["match"] = matches
+		cloneRoutes = append(cloneRoutes, cloneRoute)
+	}
+	return cloneRoutes
+}
+
+// updateSourceRoutes changes the http routes of a source VirtualService, retrying on conflicts
+func updateSourceRoutes(dynamicClient dynamic.Interface, namespace, name string, update func(routes []interface{}) []interface{}) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		virtualService, err := dynamicClient.Resource(virtualServiceGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		routes, _, _ := unstructured.NestedSlice(virtualService.Object, "spec", "http")
+		updated := update(routes)
+		if (len(updated) == 0 && len(routes) == 0) || equality.Semantic.DeepEqual(updated, routes) {
+			return nil
+		}
+		if err := unstructured.SetNestedSlice(virtualService.Object, updated, "spec", "http"); err != nil {
+			return err
+		}
+		_, err = dynamicClient.Resource(virtualServiceGVR).Namespace(namespace).Update(context.TODO(), virtualService, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// withoutCloneRoutes drops the routes added for targetNamespace, or for any clone when it is empty
+func withoutCloneRoutes(routes []interface{}, targetNamespace string) []interface{} {
+	kept := make([]interface{}, 0, len(routes))
+	for _, route := range routes {
+		if !isCloneRoute(route, targetNamespace) {
+			kept = append(kept, route)
+		}
+	}
+	return kept
+}
+
+// addHeaderRoutes puts routes to the clone in front of the routes of a source VirtualService, replacing
+// routes added by an earlier clone with the same name
+func addHeaderRoutes(dynamicClient dynamic.Interface, sourceNamespace, targetNamespace, name string, routing *HeaderRouting) (int, *Error) {
+	added := 0
+	err := updateSourceRoutes(dynamicClient, sourceNamespace, name, func(routes []interface{}) []interface{} {
+		routes = withoutCloneRoutes(routes, targetNamespace)
+		cloneRoutes := headerRoutes(routes, routing, sourceNamespace, targetNamespace)
+		added = len(cloneRoutes)
+		return append(cloneRoutes, routes...)
+	})
+	if err != nil {
+		return 0, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error adding header routes to VirtualService %s/%s: %v", sourceNamespace, name, err),
+		}
+	}
+	return added, nil
+}
+
+// removeCloneTraffic removes the header routes and mirrors to a clone from every VirtualService of its
+// source namespace. It runs before a cloned namespace is deleted.
+func removeCloneTraffic(_ *kubernetes.Clientset, dynamicClient dynamic.Interface, namespace *v1.Namespace) *Error {
+	sourceNamespace := namespace.Annotations[TARGET_NS_ANNOTATION]
+	if sourceNamespace == "" || dynamicClient == nil {
+		return nil
+	}
+	virtualServices, err := dynamicClient.Resource(virtualServiceGVR).Namespace(sourceNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Istio is not installed
+			return nil
+		}
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error listing VirtualServices in %s: %v", sourceNamespace, err),
+		}
+	}
+	cleanup := func(routes []interface{}) []interface{} {
+		return withoutCloneMirrors(withoutCloneRoutes(routes, namespace.Name), "", namespace.Name, nil)
+	}
+	for _, virtualService := range virtualServices.Items {
+		routes, _, _ := unstructured.NestedSlice(virtualService.Object, "spec", "http")
+		if len(routes) == 0 || equality.Semantic.DeepEqual(cleanup(routes), routes) {
+			continue
+		}
+		err := updateSourceRoutes(dynamicClient, sourceNamespace, virtualService.GetName(), cleanup)
+		if err != nil && !errors.IsNotFound(err) {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error removing routes to %s from VirtualService %s/%s: %v", namespace.Name, sourceNamespace, virtualService.GetName(), err),
+			}
+		}
+		log.Printf("Removed routes to %s from VirtualService %s/%s\n", namespace.Name, sourceNamespace, virtualService.GetName())
+	}
+	return nil
+}
+
+// routeHeaderToClone adds the header routes of a cloned VirtualService to its source, or plans it on a dry run
+func routeHeaderToClone(dynamicClient dynamic.Interface, sourceNamespace, targetNamespace, name string, routing *HeaderRouting, job *CloneJob) *Error {
+	reason := fmt.Sprintf("route requests with %s: %s to %s", routing.header(), routing.value(targetNamespace), targetNamespace)
+	if job.dryRun() {
+		job.planPatch("VirtualService", sourceNamespace+"/"+name, reason)
+		return nil
+	}
+	added, errObj := addHeaderRoutes(dynamicClient, sourceNamespace, targetNamespace, name, routing)
+	if errObj != nil {
+		return errObj
+	}
+	log.Printf("Added %d header routes to %s in VirtualService %s/%s\n", added, targetNamespace, sourceNamespace, name)
+	job.record("HeaderRoute", sourceNamespace+"/"+name, ObjectStatusReady, fmt.Sprintf("%d routes, %s", added, reason))
+	job.markCreated("HeaderRoute", sourceNamespace+"/"+name, virtualServiceGVR)
+	return nil
+}
+
+// MirroredRoute is a route of a source VirtualService whose traffic is mirrored to a clone
+type MirroredRoute struct {
+	VirtualService string  `json:"virtualService"`
+	Route          string  `json:"route"`
+	Host           string  `json:"host,omitempty"`
+	Percentage     float64 `json:"percentage,omitempty"`
+	// Why the route isn't mirrored
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// isCloneHost tells whether a fully qualified service host is in targetNamespace
+func isCloneHost(host, targetNamespace string) bool {
+	labels := strings.Split(host, ".")
+	return len(labels) > 1 && labels[1] == targetNamespace
+}
+
+// clonedVirtualServices returns the names of the source VirtualServices of a clone, limited to names when
+// it isn't empty. names may be either source or cloned VirtualService names.
+func clonedVirtualServices(dynamicClient dynamic.Interface, targetNamespace string, names []string) ([]string, *Error) {
+	virtualServices, err := dynamicClient.Resource(virtualServiceGVR).Namespace(targetNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, &Error{
+				Code:    http.StatusNotFound,
+				Message: "VirtualServices are not installed in the cluster",
+			}
+		}
+		return nil, &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error listing VirtualServices in %s: %v", targetNamespace, err),
+		}
+	}
+	var sources []string
+	for _, virtualService := range virtualServices.Items {
+		source := virtualService.GetAnnotations()[TARGET_VIRTUAL_SERVICE_ANNOTATION]
+		if source == "" {
+			continue
+		}
+		if len(names) > 0 && !slices.Contains(names, source) && !slices.Contains(names, virtualService.GetName()) {
+			continue
+		}
+		sources = append(sources, source)
+	}
+	if len(sources) == 0 {
+		return nil, &Error{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("No cloned VirtualServices found in %s", targetNamespace),
+		}
+	}
+	return sources, nil
+}
+
+// MirrorToClone mirrors the traffic of the source VirtualServices of a clone to the cloned services. Only
+// routes named in routes are mirrored when it isn't empty, routes already mirrored elsewhere are skipped.
+func MirrorToClone(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, targetNamespace string, virtualServices, routes []string, percentage float64) ([]MirroredRoute, *Error) {
+	if percentage == 0 {
+		percentage = 100
+	}
+	if percentage < 0 || percentage > 100 {
+		return nil, &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Invalid percentage %v, expected a value between 0 and 100", percentage),
+		}
+	}
+	sourceNamespace, errObj := getCloneSource(clientset, targetNamespace)
+	if errObj != nil {
+		return nil, errObj
+	}
+	sources, errObj := clonedVirtualServices(dynamicClient, targetNamespace, virtualServices)
+	if errObj != nil {
+		return nil, errObj
+	}
+
+	mirrored := []MirroredRoute{}
+	for _, name := range sources {
+		var results []MirroredRoute
+		err := updateSourceRoutes(dynamicClient, sourceNamespace, name, func(httpRoutes []interface{}) []interface{} {
+			results = nil
+			updated := runtime.DeepCopyJSONValue(httpRoutes).([]interface{})
+			for i, route := range updated {
+				m, ok := route.(map[string]interface{})
+				if !ok || isCloneRoute(route, "") {
+					continue
+				}
+				routeName, _ := m["name"].(string)
+				if len(routes) > 0 && !slices.Contains(routes, routeName) {
+					continue
+				}
+				if routeName == "" {
+					routeName = fmt.Sprintf("%d", i)
+				}
+				result := MirroredRoute{VirtualService: name, Route: routeName}
+				if host, _, _ := unstructured.NestedString(m, "mirror", "host"); host != "" && !isCloneHost(host, targetNamespace) {
+					result.Skipped = fmt.Sprintf("route already mirrors to %s", host)
+					results = append(results, result)
+					continue
+				}
+				destinations, _, _ := unstructured.NestedSlice(m, "route")
+				mirror := map[string]interface{}{}
+				for _, destination := range destinations {
+					d, _ := destination.(map[string]interface{})
+					host, _, _ := unstructured.NestedString(d, "destination", "host")
+					if cloneHost, ok := cloneDestinationHost(host, sourceNamespace, targetNamespace); ok {
+						// Mirror to the clone of the first destination, the mirror only takes a single host
+						mirror["host"] = cloneHost
+						if port, ok, _ := unstructured.NestedMap(d, "destination", "port"); ok {
+							mirror["port"] = port
+						}
+						break
+					}
+				}
+				if mirror["host"] == nil {
+					result.Skipped = "route has no destination in the source namespace"
+					results = append(results, result)
+					continue
+				}
+				m["mirror"] = mirror
+				m["mirrorPercentage"] = map[string]interface{}{"value": percentage}
+				result.Host = mirror["host"].(string)
+				result.Percentage = percentage
+				results = append(results, result)
+			}
+			return updated
+		})
+		if err != nil {
+			return nil, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error mirroring VirtualService %s/%s: %v", sourceNamespace, name, err),
+			}
+		}
+		log.Printf("Mirroring %d routes of VirtualService %s/%s to %s\n", len(results), sourceNamespace, name, targetNamespace)
+		mirrored = append(mirrored, results...)
+	}
+	return mirrored, nil
+}
+
+// withoutCloneMirrors removes the mirrors to targetNamespace from a list of http routes
+func withoutCloneMirrors(httpRoutes []interface{}, virtualService, targetNamespace string, removed *[]MirroredRoute) []interface{} {
+	updated := runtime.DeepCopyJSONValue(httpRoutes).([]interface{})
+	for i, route := range updated {
+		m, ok := route.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		host, _, _ := unstructured.NestedString(m, "mirror", "host")
+		if host == "" || !isCloneHost(host, targetNamespace) {
+			continue
+		}
+		delete(m, "mirror")
+		delete(m, "mirrorPercentage")
+		routeName, _ := m["name"].(string)
+		if routeName == "" {
+			routeName = fmt.Sprintf("%d", i)
+		}
+		if removed != nil {
+			*removed = append(*removed, MirroredRoute{VirtualService: virtualService, Route: routeName, Host: host})
+		}
+	}
+	return updated
+}
+
+// StopMirrorToClone removes the mirrors to a clone from its source VirtualServices
+func StopMirrorToClone(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, targetNamespace string, virtualServices []string) ([]MirroredRoute, *Error) {
+	sourceNamespace, errObj := getCloneSource(clientset, targetNamespace)
+	if errObj != nil {
+		return nil, errObj
+	}
+	sources, errObj := clonedVirtualServices(dynamicClient, targetNamespace, virtualServices)
+	if errObj != nil {
+		return nil, errObj
+	}
+	removed := []MirroredRoute{}
+	for _, name := range sources {
+		var results []MirroredRoute
+		err := updateSourceRoutes(dynamicClient, sourceNamespace, name, func(httpRoutes []interface{}) []interface{} {
+			results = nil
+			return withoutCloneMirrors(httpRoutes, name, targetNamespace, &results)
+		})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error removing mirrors from VirtualService %s/%s: %v", sourceNamespace, name, err),
+			}
+		}
+		removed = append(removed, results...)
+	}
+	log.Printf("Stopped mirroring %d routes to %s\n", len(removed), targetNamespace)
+	return removed, nil
+}
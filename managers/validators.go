@@ -2,21 +2,26 @@ package managers
 
 import (
 	"context"
+	"fmt"
 	"log"
 
 	"net/http"
 
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
 var errorCodes = map[string]int{
-	"NamespaceAnnotationMissing":  http.StatusBadRequest,
-	"DeploymentAnnotationMissing": http.StatusBadRequest,
-	"ConfigMapAnnotationMissing":  http.StatusBadRequest,
-	"SecretAnnotationMissing":     http.StatusBadRequest,
+	"NamespaceAnnotationMissing":   http.StatusBadRequest,
+	"DeploymentAnnotationMissing":  http.StatusBadRequest,
+	"ConfigMapAnnotationMissing":   http.StatusBadRequest,
+	"SecretAnnotationMissing":      http.StatusBadRequest,
+	"StatefulSetAnnotationMissing": http.StatusBadRequest,
+	"NamespaceNotCloned":           http.StatusBadRequest,
+	"SourceNamespaceProtected":     http.StatusForbidden,
 }
 
 type Error struct {
@@ -52,6 +57,79 @@ func validateSourceNamespace(clientset *kubernetes.Clientset, sourceNamespace st
 	return nil
 }
 
+// validateNamespaceDeletion only allows deleting namespaces created by CloneNamespace, never a clone source
+func validateNamespaceDeletion(namespace *v1.Namespace) *Error {
+	annotations := namespace.Annotations
+	if _, ok := annotations[NS_CLONER_ANNOTATION]; ok {
+		return &Error{
+			Code:    errorCodes["SourceNamespaceProtected"],
+			Message: fmt.Sprintf("Namespace %s is a clone source and can't be deleted", namespace.Name),
+		}
+	}
+	if annotations[TARGET_NS_ANNOTATION_ENABLED] != "true" {
+		return &Error{
+			Code:    errorCodes["NamespaceNotCloned"],
+			Message: fmt.Sprintf("Namespace %s was not created by the cloner", namespace.Name),
+		}
+	}
+	return nil
+}
+
+// validateClonedNamespace only allows operations on workloads in a namespace created by CloneNamespace
+func validateClonedNamespace(clientset *kubernetes.Clientset, namespaceName string) *Error {
+	namespace, err := clientset.CoreV1().Namespaces().Get(context.TODO(), namespaceName, metav1.GetOptions{})
+	if err != nil {
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	if namespace.Annotations[TARGET_NS_ANNOTATION_ENABLED] != "true" {
+		return &Error{
+			Code:    errorCodes["NamespaceNotCloned"],
+			Message: fmt.Sprintf("Namespace %s was not created by the cloner", namespaceName),
+		}
+	}
+	return nil
+}
+
+// getCloneSource returns the source namespace of a namespace created by the cloner, checking the source still exists
+func getCloneSource(clientset *kubernetes.Clientset, targetNamespace string) (string, *Error) {
+	namespace, err := clientset.CoreV1().Namespaces().Get(context.TODO(), targetNamespace, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", &Error{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Namespace %s not found", targetNamespace),
+			}
+		}
+		return "", &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	sourceNamespace := namespace.Annotations[TARGET_NS_ANNOTATION]
+	if namespace.Annotations[TARGET_NS_ANNOTATION_ENABLED] != "true" || sourceNamespace == "" {
+		return "", &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Namespace %s is not a clone", targetNamespace),
+		}
+	}
+	if _, err := clientset.CoreV1().Namespaces().Get(context.TODO(), sourceNamespace, metav1.GetOptions{}); err != nil {
+		if errors.IsNotFound(err) {
+			return "", &Error{
+				Code:    http.StatusNotFound,
+				Message: fmt.Sprintf("Source namespace %s of %s not found", sourceNamespace, targetNamespace),
+			}
+		}
+		return "", &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	return sourceNamespace, nil
+}
+
 func validateDeploymentEliblity(clientset *kubernetes.Clientset, deployment *appsv1.Deployment) *Error {
 	// Check if the deployment is already cloned
 	annotations := deployment.ObjectMeta.Annotations
@@ -74,6 +152,18 @@ func validateDeploymentEliblity(clientset *kubernetes.Clientset, deployment *app
 	return nil
 }
 
+// validateStatefulSetEliblity only allows operations on StatefulSets cloned by this system
+func validateStatefulSetEliblity(statefulSet *appsv1.StatefulSet) *Error {
+	annotations := statefulSet.ObjectMeta.Annotations
+	if annotations[TARGET_NS_ANNOTATION_ENABLED] != "true" || annotations[TARGET_STS_ANNOTATION] == "" {
+		return &Error{
+			Code:    errorCodes["StatefulSetAnnotationMissing"],
+			Message: "StatefulSet is not Annotated for operations",
+		}
+	}
+	return nil
+}
+
 func validateSecretEliblity(clientset *kubernetes.Clientset, secret *v1.Secret) *Error {
 	// Check if the deployment is already cloned
 	annotations := secret.ObjectMeta.Annotations
@@ -0,0 +1,487 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PVCStrategy decides how the data of a PersistentVolumeClaim is cloned
+type PVCStrategy string
+
+const (
+	// Create the claim with the same spec and a new, empty volume, the default
+	PVCStrategyEmpty PVCStrategy = "empty"
+	// Provision the volume as a CSI clone of the source claim. Needs the alpha CrossNamespaceVolumeDataSource
+	// feature gate and the Gateway API ReferenceGrant CRD, the clone fails with a bad request without them.
+	PVCStrategyClone PVCStrategy = "clone"
+	// Take a VolumeSnapshot of the source claim and restore it into the clone
+	PVCStrategySnapshot PVCStrategy = "snapshot"
+)
+
+var (
+	volumeSnapshotGVR        = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshots"}
+	volumeSnapshotContentGVR = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshotcontents"}
+	referenceGrantGVR        = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1beta1", Resource: "referencegrants"}
+)
+
+// VolumeCloning controls how PersistentVolumeClaims are cloned
+type VolumeCloning struct {
+	// Strategy for every claim, "empty" when not set
+	Strategy PVCStrategy `json:"strategy"`
+	// Per claim strategies, by claim name, overriding Strategy
+	Claims map[string]PVCStrategy `json:"claims"`
+	// VolumeSnapshotClass of the snapshots taken with the snapshot strategy, the cluster default when empty
+	VolumeSnapshotClass string `json:"volumeSnapshotClass"`
+}
+
+func (s PVCStrategy) validate() *Error {
+	switch s {
+	case "", PVCStrategyEmpty, PVCStrategyClone, PVCStrategySnapshot:
+		return nil
+	}
+	return &Error{
+		Code:    http.StatusBadRequest,
+		Message: fmt.Sprintf("Invalid PVC strategy %q, expected one of empty, clone or snapshot", s),
+	}
+}
+
+func (v *VolumeCloning) validate() *Error {
+	if v == nil {
+		return nil
+	}
+	if errObj := v.Strategy.validate(); errObj != nil {
+		return errObj
+	}
+	for _, strategy := range v.Claims {
+		if errObj := strategy.validate(); errObj != nil {
+			return errObj
+		}
+	}
+	return nil
+}
+
+// copiesData tells whether any claim is cloned with its data, which needs the clone in the source cluster
+func (v *VolumeCloning) copiesData() bool {
+	if v == nil {
+		return false
+	}
+	if v.Strategy != "" && v.Strategy != PVCStrategyEmpty {
+		return true
+	}
+	for _, strategy := range v.Claims {
+		if strategy != PVCStrategyEmpty {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *VolumeCloning) strategy(claim string) PVCStrategy {
+	if v == nil {
+		return PVCStrategyEmpty
+	}
+	if strategy, ok := v.Claims[claim]; ok && strategy != "" {
+		return strategy
+	}
+	if v.Strategy == "" {
+		return PVCStrategyEmpty
+	}
+	return v.Strategy
+}
+
+// volumeSourceName names the snapshots and grants made in the source namespace for a clone
+func volumeSourceName(name, targetNamespace string) string {
+	return "cloner-" + targetNamespace + "-" + name
+}
+
+// CloneVolumeClaims clones the PersistentVolumeClaims of the source namespace with the job's strategy. Claims
+// of StatefulSet volumeClaimTemplates keep their names and are adopted by the cloned StatefulSet.
+func CloneVolumeClaims(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	targetClientset := job.targetClientset(clientset)
+	claims, err := clientset.CoreV1().PersistentVolumeClaims(sourceNamespace).List(context.TODO(), job.listOptions())
+	if err != nil {
+		log.Printf("Error checking for PersistentVolumeClaims: %v\n", err)
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		}
+	}
+	// Empty claims of StatefulSets follow the storage class override of their volumeClaimTemplates
+	storageClass := ""
+	var statefulSets []appsv1.StatefulSet
+	if overrides := job.statefulSetOverrides(); overrides != nil && overrides.StorageClass != "" {
+		list, err := clientset.AppsV1().StatefulSets(sourceNamespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error listing StatefulSets in %s: %v", sourceNamespace, err),
+			}
+		}
+		storageClass, statefulSets = overrides.StorageClass, list.Items
+	}
+	for _, claim := range claims.Items {
+		if !job.matchesName(claim.Name) || claim.DeletionTimestamp != nil {
+			continue
+		}
+		_, err := targetClientset.CoreV1().PersistentVolumeClaims(targetNamespace).Get(context.TODO(), claim.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error checking for existing PersistentVolumeClaim %s: %v", claim.Name, err),
+			}
+		} else if err == nil {
+			log.Printf("PersistentVolumeClaim %s already exists in %s, skipping creation\n", claim.Name, targetNamespace)
+			job.skip("PersistentVolumeClaim", claim.Name, "already exists")
+			continue
+		}
+
+		strategy := job.volumeCloning().strategy(claim.Name)
+		message := "empty volume"
+		if strategy != PVCStrategyEmpty && claim.Status.Phase != v1.ClaimBound {
+			// There is no data to copy until the claim is bound
+			message = fmt.Sprintf("empty volume, source claim is %s", claim.Status.Phase)
+			strategy = PVCStrategyEmpty
+		}
+
+		spec := *claim.Spec.DeepCopy()
+		// The volume and data source belong to the source claim
+		spec.VolumeName = ""
+		spec.DataSource = nil
+		spec.DataSourceRef = nil
+		mutations := make(map[string]interface{})
+		switch strategy {
+		case PVCStrategyEmpty:
+			if storageClass != "" && isStatefulSetClaim(claim.Name, statefulSets) {
+				spec.StorageClassName = &storageClass
+				mutations["spec.storageClassName"] = storageClass
+			}
+		case PVCStrategyClone:
+			if errObj := grantClaimReferences(dynamicClient, sourceNamespace, targetNamespace, job); errObj != nil {
+				return errObj
+			}
+			namespace := sourceNamespace
+			spec.DataSourceRef = &v1.TypedObjectReference{
+				Kind:      "PersistentVolumeClaim",
+				Name:      claim.Name,
+				Namespace: &namespace,
+			}
+			message = fmt.Sprintf("cloned from %s/%s", sourceNamespace, claim.Name)
+		case PVCStrategySnapshot:
+			snapshot, errObj := restoreSnapshot(dynamicClient, claim.Name, sourceNamespace, targetNamespace, job)
+			if errObj != nil {
+				job.record("PersistentVolumeClaim", claim.Name, ObjectStatusFailed, errObj.Message)
+				return errObj
+			}
+			apiGroup := volumeSnapshotGVR.Group
+			spec.DataSource = &v1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     "VolumeSnapshot",
+				Name:     snapshot,
+			}
+			message = fmt.Sprintf("restored from snapshot %s/%s", sourceNamespace, snapshot)
+		}
+
+		annotations := make(map[string]string)
+		annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+		annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+		annotations[TARGET_PVC_ANNOTATION] = claim.Name
+		annotations[TARGET_PVC_STRATEGY_ANNOTATION] = string(strategy)
+		newClaim := &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        claim.Name,
+				Namespace:   targetNamespace,
+				Labels:      claim.Labels,
+				Annotations: annotations,
+			},
+			Spec: spec,
+		}
+		var createdClaim *v1.PersistentVolumeClaim
+		created, errObj := job.create("PersistentVolumeClaim", claim.Name, annotations, mutations, func(opts metav1.CreateOptions) error {
+			var err error
+			createdClaim, err = targetClientset.CoreV1().PersistentVolumeClaims(targetNamespace).Create(context.TODO(), newClaim, opts)
+			return err
+		})
+		if errObj != nil {
+			return errObj
+		}
+		if !created {
+			continue
+		}
+		if strategy == PVCStrategyClone && (createdClaim.Spec.DataSourceRef == nil || createdClaim.Spec.DataSourceRef.Namespace == nil) {
+			// The API server drops cross namespace data sources without the feature gate, leaving an empty claim
+			if err := targetClientset.CoreV1().PersistentVolumeClaims(targetNamespace).Delete(context.TODO(), claim.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				log.Printf("Error deleting PersistentVolumeClaim %s: %v\n", claim.Name, err)
+			}
+			errObj := &Error{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("PersistentVolumeClaim %s can't use the clone strategy, the CrossNamespaceVolumeDataSource feature gate is not enabled", claim.Name),
+			}
+			job.record("PersistentVolumeClaim", claim.Name, ObjectStatusFailed, errObj.Message)
+			return errObj
+		}
+		// Claims are not waited for, with WaitForFirstConsumer they only bind once a pod uses them
+		log.Printf("PersistentVolumeClaim %s cloned to namespace %s (%s)\n", claim.Name, targetNamespace, message)
+		job.record("PersistentVolumeClaim", claim.Name, ObjectStatusCreated, message)
+	}
+	return nil
+}
+
+// grantClaimReferences lets claims in the target namespace use claims of the source namespace as their data
+// source. Cross namespace data sources need the CrossNamespaceVolumeDataSource feature gate and a CSI driver
+// supporting clones.
+func grantClaimReferences(dynamicClient dynamic.Interface, sourceNamespace, targetNamespace string, job *CloneJob) *Error {
+	name := volumeSourceName("pvcs", targetNamespace)
+	grants := dynamicClient.Resource(referenceGrantGVR).Namespace(sourceNamespace)
+	if _, err := grants.List(context.TODO(), metav1.ListOptions{Limit: 1}); errors.IsNotFound(err) {
+		return &Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("The clone strategy needs the Gateway API ReferenceGrant CRD (%s), it is not installed", referenceGrantGVR.GroupResource().String()),
+		}
+	}
+	_, err := grants.Get(context.TODO(), name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	} else if !errors.IsNotFound(err) {
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error checking for ReferenceGrant %s: %v", name, err),
+		}
+	}
+	if job.dryRun() {
+		job.planPatch("ReferenceGrant", name, fmt.Sprintf("allow claims in %s to clone claims of %s", targetNamespace, sourceNamespace))
+		return nil
+	}
+	grant := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": referenceGrantGVR.GroupVersion().String(),
+		"kind":       "ReferenceGrant",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": sourceNamespace,
+			"labels":    map[string]interface{}{TARGET_NAMESPACE_LABEL: targetNamespace},
+		},
+		"spec": map[string]interface{}{
+			"from": []interface{}{
+				map[string]interface{}{"group": "", "kind": "PersistentVolumeClaim", "namespace": targetNamespace},
+			},
+			"to": []interface{}{
+				map[string]interface{}{"group": "", "kind": "PersistentVolumeClaim"},
+			},
+		},
+	}}
+	if _, err := grants.Create(context.TODO(), grant, metav1.CreateOptions{}); err != nil {
+		return &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error creating ReferenceGrant %s in %s: %v", name, sourceNamespace, err),
+		}
+	}
+	log.Printf("ReferenceGrant %s created in %s for namespace %s\n", name, sourceNamespace, targetNamespace)
+	return nil
+}
+
+// restoreSnapshot snapshots a claim of the source namespace and makes the snapshot available in the target
+// namespace, through a pre-provisioned VolumeSnapshotContent pointing at the same storage snapshot. The name of
+// the VolumeSnapshot in the target namespace is returned.
+func restoreSnapshot(dynamicClient dynamic.Interface, claim, sourceNamespace, targetNamespace string, job *CloneJob) (string, *Error) {
+	name := volumeSourceName(claim, targetNamespace)
+	snapshots := dynamicClient.Resource(volumeSnapshotGVR).Namespace(sourceNamespace)
+	_, err := snapshots.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error checking for VolumeSnapshot %s: %v", name, err),
+		}
+	}
+	if errors.IsNotFound(err) {
+		if job.dryRun() {
+			job.planPatch("VolumeSnapshot", name, fmt.Sprintf("snapshot claim %s in %s", claim, sourceNamespace))
+			return name, nil
+		}
+		spec := map[string]interface{}{
+			"source": map[string]interface{}{"persistentVolumeClaimName": claim},
+		}
+		if class := job.volumeCloning().VolumeSnapshotClass; class != "" {
+			spec["volumeSnapshotClassName"] = class
+		}
+		snapshot := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": volumeSnapshotGVR.GroupVersion().String(),
+			"kind":       "VolumeSnapshot",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": sourceNamespace,
+				"labels":    map[string]interface{}{TARGET_NAMESPACE_LABEL: targetNamespace},
+			},
+			"spec": spec,
+		}}
+		if _, err := snapshots.Create(context.TODO(), snapshot, metav1.CreateOptions{}); err != nil {
+			return "", &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error creating VolumeSnapshot %s of claim %s: %v", name, claim, err),
+			}
+		}
+		log.Printf("VolumeSnapshot %s of claim %s created in %s\n", name, claim, sourceNamespace)
+	} else if job.dryRun() {
+		return name, nil
+	}
+
+	contentName, errObj := waitForVolumeSnapshot(dynamicClient, sourceNamespace, name, job)
+	if errObj != nil {
+		return "", errObj
+	}
+	content, err := dynamicClient.Resource(volumeSnapshotContentGVR).Get(context.TODO(), contentName, metav1.GetOptions{})
+	if err != nil {
+		return "", &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error getting VolumeSnapshotContent %s: %v", contentName, err),
+		}
+	}
+	driver, _, _ := unstructured.NestedString(content.Object, "spec", "driver")
+	snapshotHandle, _, _ := unstructured.NestedString(content.Object, "status", "snapshotHandle")
+	snapshotClass, _, _ := unstructured.NestedString(content.Object, "spec", "volumeSnapshotClassName")
+
+	// The copy is retained when the clone's snapshot is deleted, the storage snapshot belongs to the source
+	contentSpec := map[string]interface{}{
+		"deletionPolicy": "Retain",
+		"driver":         driver,
+		"source":         map[string]interface{}{"snapshotHandle": snapshotHandle},
+		"volumeSnapshotRef": map[string]interface{}{
+			"name":      name,
+			"namespace": targetNamespace,
+		},
+	}
+	if snapshotClass != "" {
+		contentSpec["volumeSnapshotClassName"] = snapshotClass
+	}
+	restoredContent := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": volumeSnapshotContentGVR.GroupVersion().String(),
+		"kind":       "VolumeSnapshotContent",
+		"metadata": map[string]interface{}{
+			"name":   name,
+			"labels": map[string]interface{}{TARGET_NAMESPACE_LABEL: targetNamespace},
+		},
+		"spec": contentSpec,
+	}}
+	_, err = dynamicClient.Resource(volumeSnapshotContentGVR).Create(context.TODO(), restoredContent, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return "", &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error creating VolumeSnapshotContent %s: %v", name, err),
+		}
+	}
+
+	annotations := make(map[string]string)
+	annotations[TARGET_NS_ANNOTATION] = sourceNamespace
+	annotations[TARGET_NS_ANNOTATION_ENABLED] = "true"
+	annotations[TARGET_PVC_ANNOTATION] = claim
+	restoredSnapshot := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": volumeSnapshotGVR.GroupVersion().String(),
+		"kind":       "VolumeSnapshot",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": targetNamespace,
+		},
+		"spec": map[string]interface{}{
+			"source": map[string]interface{}{"volumeSnapshotContentName": name},
+		},
+	}}
+	restoredSnapshot.SetAnnotations(annotations)
+	_, err = dynamicClient.Resource(volumeSnapshotGVR).Namespace(targetNamespace).Create(context.TODO(), restoredSnapshot, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return "", &Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Error creating VolumeSnapshot %s in %s: %v", name, targetNamespace, err),
+		}
+	}
+	job.record("VolumeSnapshot", name, ObjectStatusReady, fmt.Sprintf("snapshot of %s/%s", sourceNamespace, claim))
+	if err == nil {
+		job.markCreated("VolumeSnapshot", name, volumeSnapshotGVR)
+	}
+	return name, nil
+}
+
+// waitForVolumeSnapshot waits until a snapshot is ready to use and returns the name of its VolumeSnapshotContent
+func waitForVolumeSnapshot(dynamicClient dynamic.Interface, namespace, name string, job *CloneJob) (string, *Error) {
+	snapshots := dynamicClient.Resource(volumeSnapshotGVR).Namespace(namespace)
+	lw := nameListWatch(name,
+		func(options metav1.ListOptions) (runtime.Object, error) {
+			return snapshots.List(job.context(), options)
+		},
+		func(options metav1.ListOptions) (watch.Interface, error) {
+			return snapshots.Watch(job.context(), options)
+		})
+	contentName := ""
+	errObj := waitUntil(job.context(), job.readinessTimeout("VolumeSnapshot"), "VolumeSnapshot", name, "ready to use", lw, &unstructured.Unstructured{}, nil, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, &readinessFailure{fmt.Sprintf("VolumeSnapshot %s was deleted while waiting for it to be ready to use", name)}
+		}
+		snapshot := event.Object.(*unstructured.Unstructured)
+		if message, found, _ := unstructured.NestedString(snapshot.Object, "status", "error", "message"); found && message != "" {
+			return false, &readinessFailure{fmt.Sprintf("VolumeSnapshot %s has failed: %s", name, message)}
+		}
+		readyToUse, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+		contentName, _, _ = unstructured.NestedString(snapshot.Object, "status", "boundVolumeSnapshotContentName")
+		if !readyToUse || contentName == "" {
+			log.Printf("Waiting for VolumeSnapshot %s to be ready to use...\n", name)
+			job.record("VolumeSnapshot", name, ObjectStatusCreated, "waiting to be ready to use")
+			return false, nil
+		}
+		return true, nil
+	})
+	if errObj != nil {
+		job.record("VolumeSnapshot", name, ObjectStatusFailed, errObj.Message)
+		return "", errObj
+	}
+	return contentName, nil
+}
+
+// removeVolumeSources deletes the snapshots, snapshot contents and grants made for a clone's claims. It runs
+// before a cloned namespace is deleted.
+func removeVolumeSources(_ *kubernetes.Clientset, dynamicClient dynamic.Interface, namespace *v1.Namespace) *Error {
+	sourceNamespace := namespace.Annotations[TARGET_NS_ANNOTATION]
+	if sourceNamespace == "" || dynamicClient == nil {
+		return nil
+	}
+	selector := labels.SelectorFromSet(labels.Set{TARGET_NAMESPACE_LABEL: namespace.Name}).String()
+	resources := []dynamic.ResourceInterface{
+		dynamicClient.Resource(volumeSnapshotGVR).Namespace(sourceNamespace),
+		dynamicClient.Resource(volumeSnapshotContentGVR),
+		dynamicClient.Resource(referenceGrantGVR).Namespace(sourceNamespace),
+	}
+	for _, resource := range resources {
+		items, err := resource.List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				// The CRD is not installed
+				continue
+			}
+			return &Error{
+				Code:    http.StatusInternalServerError,
+				Message: fmt.Sprintf("Error listing volume sources of %s: %v", namespace.Name, err),
+			}
+		}
+		for _, item := range items.Items {
+			err := resource.Delete(context.TODO(), item.GetName(), metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return &Error{
+					Code:    http.StatusInternalServerError,
+					Message: fmt.Sprintf("Error deleting %s %s: %v", item.GetKind(), item.GetName(), err),
+				}
+			}
+			log.Printf("Deleted %s %s of namespace %s\n", item.GetKind(), item.GetName(), namespace.Name)
+		}
+	}
+	return nil
+}
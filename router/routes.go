@@ -24,7 +24,24 @@ func InitializeRoutes(clientset *kubernetes.Clientset, dynamicClientSet *dynamic
 		v1.GET("/namespaces/:namespace/configmaps/display", controllers.DisplayConfigMap)
 
 		v1.POST("/namespaces/:namespace/cloneNamespace", controllers.CloneNamespace)
+		v1.POST("/namespaces/:namespace/resync", controllers.ResyncNamespace)
+		v1.GET("/namespaces/:namespace/drift", controllers.GetNamespaceDrift)
+		v1.POST("/namespaces/:namespace/extend", controllers.ExtendNamespaceExpiry)
+		v1.DELETE("/namespaces/:namespace", controllers.DeleteNamespace)
+		v1.GET("/deletions/:id", controllers.GetDeletion)
+		v1.POST("/namespaces/:namespace/scale", controllers.ScaleNamespace)
+		v1.POST("/namespaces/:namespace/wake", controllers.WakeNamespace)
+		v1.POST("/namespaces/:namespace/mirror", controllers.MirrorToClone)
+		v1.DELETE("/namespaces/:namespace/mirror", controllers.StopMirrorToClone)
+		v1.GET("/clones", controllers.ListCloneJobs)
+		v1.GET("/clones/:id", controllers.GetCloneJob)
+		v1.POST("/clones/:id/abort", controllers.AbortCloneJob)
+		v1.GET("/clusters", controllers.ListClusters)
 		v1.POST("/deployments/:deployment", controllers.UpdateDeploymentImage)
+		v1.POST("/deployments/:deployment/scaleup", controllers.ScaleUpDeployment)
+		v1.POST("/deployments/:deployment/scaledown", controllers.ScaleDownDeployment)
+		v1.POST("/statefulsets/:statefulset/scaleup", controllers.ScaleUpStatefulSet)
+		v1.POST("/statefulsets/:statefulset/scaledown", controllers.ScaleDownStatefulSet)
 		v1.POST("/secrets/:secret", controllers.UpdateSecret)
 		v1.POST("/configmaps/:configmap", controllers.UpdateConfigMap)
 